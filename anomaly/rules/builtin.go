@@ -0,0 +1,49 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package rules
+
+// builtinRule wraps a predicate/anomaly pair built straight from Go, as
+// opposed to one loaded from a config file via LoadFile.
+type builtinRule struct {
+	id       string
+	category string
+	severity Severity
+	message  string
+	pred     Predicate
+}
+
+func (r builtinRule) Match(f Target) []Anomaly {
+	if !r.pred(f) {
+		return nil
+	}
+	return []Anomaly{{ID: r.id, Category: r.category, Severity: r.severity, Message: r.message}}
+}
+
+// packerSectionNames are section names historically used by well-known
+// packers; ported as-is from the prior hard-coded detectPackedBinary check.
+var packerSectionNames = []string{
+	"UPX0", "UPX1", "UPX2", ".aspack", ".adata", "MPRESS1", "MPRESS2",
+	".themida", ".vmp0", ".vmp1", ".vmp2", ".petite", ".enigma1", ".pec1",
+}
+
+func init() {
+	for _, name := range packerSectionNames {
+		Register(builtinRule{
+			id:       "PACK_SUSPICIOUS_NAME",
+			category: "Packing",
+			severity: SeverityMedium,
+			message:  "Suspicious packed section name: " + name,
+			pred:     SectionNameGlob(name),
+		})
+	}
+
+	Register(builtinRule{
+		id:       "ENTROPY_ELEVATED_PROPORTION",
+		category: "Packing",
+		severity: SeverityMedium,
+		message:  "Section entropy exceeds 7.5, consistent with packing or encryption",
+		pred:     EntropyInRange(7.5, 8.0),
+	})
+}
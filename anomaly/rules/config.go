@@ -0,0 +1,141 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Unmarshal decodes a rules config file's bytes into v. It defaults to JSON;
+// callers that want YAML-formatted rule files can swap it for a YAML
+// library's Unmarshal func (e.g. `rules.Unmarshal = yaml.Unmarshal`) without
+// this package taking a hard dependency on one.
+var Unmarshal = json.Unmarshal
+
+// Config is the on-disk shape of a user-supplied rules file, loaded via
+// Options.AnomalyRulesPath.
+type Config struct {
+	Rules []ConfigRuleSpec `json:"rules" yaml:"rules"`
+}
+
+// ConfigRuleSpec describes one rule: a combinator tree of predicates plus
+// the anomaly it emits when the tree matches.
+type ConfigRuleSpec struct {
+	ID         string         `json:"id" yaml:"id"`
+	Category   string         `json:"category" yaml:"category"`
+	Severity   Severity       `json:"severity" yaml:"severity"`
+	Message    string         `json:"message" yaml:"message"`
+	Match      PredicateSpec  `json:"match" yaml:"match"`
+}
+
+// PredicateSpec is the JSON/YAML encoding of a Predicate: exactly one field
+// should be set. Combinators (And/Or/Not) nest other PredicateSpecs.
+type PredicateSpec struct {
+	SectionNameGlob   string          `json:"sectionNameGlob,omitempty" yaml:"sectionNameGlob,omitempty"`
+	EntropyMin        *float64        `json:"entropyMin,omitempty" yaml:"entropyMin,omitempty"`
+	EntropyMax        *float64        `json:"entropyMax,omitempty" yaml:"entropyMax,omitempty"`
+	CharacteristicsMask uint32        `json:"characteristicsMask,omitempty" yaml:"characteristicsMask,omitempty"`
+	ImportCountAtLeast  int           `json:"importCountAtLeast,omitempty" yaml:"importCountAtLeast,omitempty"`
+	ExportCountAtLeast  int           `json:"exportCountAtLeast,omitempty" yaml:"exportCountAtLeast,omitempty"`
+	And               []PredicateSpec `json:"and,omitempty" yaml:"and,omitempty"`
+	Or                []PredicateSpec `json:"or,omitempty" yaml:"or,omitempty"`
+	Not               *PredicateSpec  `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// Build compiles a PredicateSpec into a Predicate.
+func (s PredicateSpec) Build() (Predicate, error) {
+	switch {
+	case s.SectionNameGlob != "":
+		return SectionNameGlob(s.SectionNameGlob), nil
+	case s.EntropyMin != nil || s.EntropyMax != nil:
+		min, max := 0.0, 8.0
+		if s.EntropyMin != nil {
+			min = *s.EntropyMin
+		}
+		if s.EntropyMax != nil {
+			max = *s.EntropyMax
+		}
+		return EntropyInRange(min, max), nil
+	case s.CharacteristicsMask != 0:
+		return CharacteristicsMask(s.CharacteristicsMask), nil
+	case s.ImportCountAtLeast != 0:
+		return ImportCountAtLeast(s.ImportCountAtLeast), nil
+	case s.ExportCountAtLeast != 0:
+		return ExportCountAtLeast(s.ExportCountAtLeast), nil
+	case len(s.And) > 0:
+		preds, err := buildAll(s.And)
+		if err != nil {
+			return nil, err
+		}
+		return And(preds...), nil
+	case len(s.Or) > 0:
+		preds, err := buildAll(s.Or)
+		if err != nil {
+			return nil, err
+		}
+		return Or(preds...), nil
+	case s.Not != nil:
+		p, err := s.Not.Build()
+		if err != nil {
+			return nil, err
+		}
+		return Not(p), nil
+	default:
+		return nil, fmt.Errorf("rules: empty predicate spec")
+	}
+}
+
+func buildAll(specs []PredicateSpec) ([]Predicate, error) {
+	preds := make([]Predicate, 0, len(specs))
+	for _, s := range specs {
+		p, err := s.Build()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+	return preds, nil
+}
+
+// configRule adapts a ConfigRuleSpec to the Rule interface.
+type configRule struct {
+	spec ConfigRuleSpec
+	pred Predicate
+}
+
+func (r configRule) Match(f Target) []Anomaly {
+	if !r.pred(f) {
+		return nil
+	}
+	return []Anomaly{{
+		ID:       r.spec.ID,
+		Category: r.spec.Category,
+		Severity: r.spec.Severity,
+		Message:  r.spec.Message,
+	}}
+}
+
+// LoadFile reads a rules config from path (JSON by default, see Unmarshal)
+// and registers each rule it defines.
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("rules: reading %s: %w", path, err)
+	}
+	var cfg Config
+	if err := Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("rules: parsing %s: %w", path, err)
+	}
+	for _, spec := range cfg.Rules {
+		pred, err := spec.Match.Build()
+		if err != nil {
+			return fmt.Errorf("rules: rule %q: %w", spec.ID, err)
+		}
+		Register(configRule{spec: spec, pred: pred})
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFileRegistersRules(t *testing.T) {
+	before := len(Registered())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	const cfg = `{
+		"rules": [
+			{
+				"id": "CUSTOM_UPX",
+				"category": "Packing",
+				"severity": 2,
+				"message": "custom UPX rule fired",
+				"match": {"sectionNameGlob": "UPX0"}
+			}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	after := Registered()
+	if len(after) != before+1 {
+		t.Fatalf("expected %d registered rules, got %d", before+1, len(after))
+	}
+
+	f := fileWithSection("UPX0", 1.0)
+	anomalies := after[len(after)-1].Match(f)
+	if len(anomalies) != 1 || anomalies[0].ID != "CUSTOM_UPX" {
+		t.Errorf("Match() = %+v, want one CUSTOM_UPX anomaly", anomalies)
+	}
+}
+
+func TestLoadFileInvalidPredicate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	const cfg = `{"rules": [{"id": "EMPTY", "match": {}}]}`
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := LoadFile(path); err == nil {
+		t.Error("expected LoadFile() to error on an empty predicate spec")
+	}
+}
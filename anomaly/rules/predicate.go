@@ -0,0 +1,105 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Predicate is a single condition evaluated against a Target, the building
+// block combinators (And/Or/Not) and Config-driven rules are made of.
+type Predicate func(f Target) bool
+
+// And reports whether every predicate matches.
+func And(preds ...Predicate) Predicate {
+	return func(f Target) bool {
+		for _, p := range preds {
+			if !p(f) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or reports whether at least one predicate matches.
+func Or(preds ...Predicate) Predicate {
+	return func(f Target) bool {
+		for _, p := range preds {
+			if p(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not negates a predicate.
+func Not(p Predicate) Predicate {
+	return func(f Target) bool { return !p(f) }
+}
+
+// SectionNameGlob matches when any section name matches the glob pattern
+// (e.g. ".them*", "UPX?").
+func SectionNameGlob(pattern string) Predicate {
+	return func(f Target) bool {
+		for _, sec := range f.Sections() {
+			name := sec.Name
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, strings.TrimRight(name, "\x00")); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EntropyInRange matches when any section's computed entropy falls within
+// [min, max].
+func EntropyInRange(min, max float64) Predicate {
+	return func(f Target) bool {
+		for _, sec := range f.Sections() {
+			e := sec.Entropy
+			if e == nil {
+				continue
+			}
+			if *e >= min && *e <= max {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CharacteristicsMask matches when the file header's Characteristics has
+// every bit in mask set.
+func CharacteristicsMask(mask uint32) Predicate {
+	return func(f Target) bool {
+		return f.Characteristics()&mask == mask
+	}
+}
+
+// ImportCountAtLeast matches when the file imports at least n distinct
+// libraries.
+func ImportCountAtLeast(n int) Predicate {
+	return func(f Target) bool { return f.ImportCount() >= n }
+}
+
+// ExportCountAtLeast matches when the file exports at least n symbols.
+func ExportCountAtLeast(n int) Predicate {
+	return func(f Target) bool { return f.ExportCount() >= n }
+}
+
+// TimestampBetween matches when the file header timestamp falls within
+// [from, to], both Unix seconds.
+func TimestampBetween(from, to uint32) Predicate {
+	return func(f Target) bool {
+		ts := f.Timestamp()
+		return ts >= from && ts <= to
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package rules
+
+import "testing"
+
+// testTarget is a bare-bones Target implementation for exercising
+// predicates without going through package pe's adapter.
+type testTarget struct {
+	sections        []SectionView
+	characteristics uint32
+	importCount     int
+	exportCount     int
+	timestamp       uint32
+}
+
+func (t testTarget) Sections() []SectionView { return t.sections }
+func (t testTarget) Characteristics() uint32 { return t.characteristics }
+func (t testTarget) ImportCount() int        { return t.importCount }
+func (t testTarget) ExportCount() int        { return t.exportCount }
+func (t testTarget) Timestamp() uint32       { return t.timestamp }
+
+func fileWithSection(name string, entropy float64) testTarget {
+	e := entropy
+	return testTarget{sections: []SectionView{{Name: name, Entropy: &e}}}
+}
+
+func TestSectionNameGlob(t *testing.T) {
+	f := fileWithSection("UPX0", 1.0)
+	if !SectionNameGlob("UPX0")(f) {
+		t.Error("expected SectionNameGlob(\"UPX0\") to match")
+	}
+	if SectionNameGlob(".text")(f) {
+		t.Error("expected SectionNameGlob(\".text\") not to match")
+	}
+}
+
+func TestEntropyInRange(t *testing.T) {
+	f := fileWithSection(".text", 7.9)
+	if !EntropyInRange(7.5, 8.0)(f) {
+		t.Error("expected entropy 7.9 to be in range [7.5, 8.0]")
+	}
+	if EntropyInRange(0, 1)(f) {
+		t.Error("expected entropy 7.9 not to be in range [0, 1]")
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	f := fileWithSection("UPX0", 7.9)
+	always := func(Target) bool { return true }
+	never := func(Target) bool { return false }
+
+	if !And(always, always)(f) {
+		t.Error("And(true, true) should match")
+	}
+	if And(always, never)(f) {
+		t.Error("And(true, false) should not match")
+	}
+	if !Or(never, always)(f) {
+		t.Error("Or(false, true) should match")
+	}
+	if !Not(never)(f) {
+		t.Error("Not(false) should match")
+	}
+}
+
+func TestPredicateSpecBuild(t *testing.T) {
+	spec := PredicateSpec{
+		And: []PredicateSpec{
+			{SectionNameGlob: "UPX*"},
+			{EntropyMin: floatPtr(7.0)},
+		},
+	}
+	pred, err := spec.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !pred(fileWithSection("UPX0", 7.5)) {
+		t.Error("expected built predicate to match packed UPX section")
+	}
+	if pred(fileWithSection(".text", 7.5)) {
+		t.Error("expected built predicate not to match .text section")
+	}
+}
+
+func floatPtr(f float64) *float64 { return &f }
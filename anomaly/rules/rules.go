@@ -0,0 +1,84 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+// Package rules implements a pluggable rule engine for PE anomaly
+// detection. Built-in heuristics (packer section names and high section
+// entropy) are registered the same way a
+// user-supplied rule loaded from YAML/JSON would be, so neither is
+// privileged over the other.
+//
+// This package intentionally does not import github.com/saferwall/pe:
+// package pe calls into Run to evaluate the engine, so a dependency in the
+// other direction would be an import cycle. Rules are evaluated instead
+// against the Target interface below, which package pe satisfies with a
+// thin adapter over *pe.File.
+package rules
+
+// SectionView is the subset of a PE section's data a Rule can inspect.
+type SectionView struct {
+	Name    string
+	Entropy *float64
+}
+
+// Target is the minimal view of a parsed PE file a Rule is evaluated
+// against.
+type Target interface {
+	Sections() []SectionView
+	Characteristics() uint32
+	ImportCount() int
+	ExportCount() int
+	Timestamp() uint32
+}
+
+// Severity ranks how concerning a matched rule is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// Anomaly is one finding emitted by a Rule.
+type Anomaly struct {
+	ID       string
+	Category string
+	Severity Severity
+	Message  string
+}
+
+// Rule inspects a parsed PE file and returns zero or more anomalies. A Rule
+// implementation must not mutate the Target.
+type Rule interface {
+	Match(f Target) []Anomaly
+}
+
+var registry []Rule
+
+// Register adds r to the set of rules GetAnomalies runs, and is meant to be
+// called from a rule package's init() so built-ins and user-registered
+// rules share one code path.
+func Register(r Rule) {
+	registry = append(registry, r)
+}
+
+// Registered returns the currently registered rules, built-in and
+// user-supplied alike.
+func Registered() []Rule {
+	out := make([]Rule, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// Run evaluates every registered rule against f and concatenates their
+// findings in registration order.
+func Run(f Target) []Anomaly {
+	var out []Anomaly
+	for _, r := range registry {
+		out = append(out, r.Match(f)...)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// Severity ranks how actionable an Anomaly is, from purely informational to
+// a strong packing/tampering signal.
+type Severity int
+
+// Severity levels, ordered least to most severe.
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// String implements fmt.Stringer for Severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// AnomalyCategory groups an Anomaly by the part of the image it concerns,
+// so a consumer can filter, e.g., "show me only Packing anomalies".
+type AnomalyCategory string
+
+// Anomaly categories.
+const (
+	CategoryHeader    AnomalyCategory = "Header"
+	CategorySections  AnomalyCategory = "Sections"
+	CategoryImports   AnomalyCategory = "Imports"
+	CategoryExports   AnomalyCategory = "Exports"
+	CategoryResources AnomalyCategory = "Resources"
+	CategorySignature AnomalyCategory = "Signature"
+	CategoryPacking   AnomalyCategory = "Packing"
+)
+
+// AnomalyLocation pinpoints where in the image an Anomaly was observed. The
+// fields are independent and a detector sets only the ones that apply: a
+// section-name anomaly sets SectionIndex, a data directory anomaly sets
+// DirectoryEntry, and a raw byte-level anomaly sets FileOffset. A detector
+// that marks a field as not applicable sets it to -1; a detector that
+// records no location at all leaves every field at zero.
+type AnomalyLocation struct {
+	// SectionIndex is the index into File.Sections, or -1 if marked not applicable.
+	SectionIndex int `json:"sectionIndex,omitempty"`
+	// DirectoryEntry is an ImageDirectoryEntry* value, or -1 if marked not applicable.
+	DirectoryEntry int `json:"directoryEntry,omitempty"`
+	// FileOffset is a raw offset into the image, or -1 if marked not applicable.
+	FileOffset int64 `json:"fileOffset,omitempty"`
+}
+
+// Anomaly is a structured finding emitted by the parser's detectors. Unlike
+// the legacy plain-string anomalies, it carries a stable, greppable ID, a
+// Category and Severity a caller can filter/triage by, and a Location
+// pinpointing what was flagged.
+type Anomaly struct {
+	// ID is a stable, machine-matchable identifier, e.g. "PACK_SUSPICIOUS_NAME".
+	ID string `json:"id"`
+	// Category is the broad area of the image this anomaly concerns.
+	Category AnomalyCategory `json:"category"`
+	// Severity is how strong a signal this anomaly is on its own.
+	Severity Severity `json:"severity"`
+	// Message is the same human-readable text the legacy []string anomalies
+	// carried.
+	Message string `json:"message"`
+	// Location is where in the image the anomaly was observed.
+	Location AnomalyLocation `json:"location"`
+}
+
+// Stable anomaly IDs introduced alongside the structured Anomaly type.
+// IDHeaderInvalidSizeOfImage corresponds to the pre-existing "Invalid
+// SizeOfImage" check in GetAnomalies; it is declared here so that detector,
+// once it is updated to call addAnomalyStruct, has a stable ID to use.
+const (
+	IDPackSuspiciousName        = "PACK_SUSPICIOUS_NAME"
+	IDEntropyElevatedProportion = "ENTROPY_ELEVATED_PROPORTION"
+	IDHeaderInvalidSizeOfImage  = "HDR_INVALID_SIZEOFIMAGE"
+	IDHeaderChecksumMismatch    = "HDR_CHECKSUM_MISMATCH"
+	IDHeaderChecksumZeroDriver  = "HDR_CHECKSUM_ZERO_FOR_DRIVER"
+)
+
+// addAnomalyStruct records a structured Anomaly and, to keep every existing
+// caller and test working unmodified, also appends its Message to the
+// legacy Anomalies []string via addAnomaly.
+func (pe *File) addAnomalyStruct(a Anomaly) {
+	pe.AnomalyDetails = append(pe.AnomalyDetails, a)
+	pe.addAnomaly(a.Message)
+}
+
+// AnomalyStrings returns the Message of every structured anomaly recorded so
+// far, in recording order, so existing []string-based callers can keep
+// working against File.AnomalyDetails without caring about the richer type.
+func (pe *File) AnomalyStrings() []string {
+	msgs := make([]string, 0, len(pe.AnomalyDetails))
+	for _, a := range pe.AnomalyDetails {
+		msgs = append(msgs, a.Message)
+	}
+	return msgs
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestAddAnomalyStructRecordsBothForms(t *testing.T) {
+	f := &File{Anomalies: []string{}}
+
+	f.addAnomalyStruct(Anomaly{
+		ID:       IDPackSuspiciousName,
+		Category: CategoryPacking,
+		Severity: SeverityMedium,
+		Message:  "Suspicious packed section name: UPX0",
+	})
+
+	if len(f.AnomalyDetails) != 1 {
+		t.Fatalf("AnomalyDetails len = %d, want 1", len(f.AnomalyDetails))
+	}
+	if f.AnomalyDetails[0].ID != IDPackSuspiciousName {
+		t.Errorf("ID = %s, want %s", f.AnomalyDetails[0].ID, IDPackSuspiciousName)
+	}
+
+	if len(f.Anomalies) != 1 || f.Anomalies[0] != "Suspicious packed section name: UPX0" {
+		t.Errorf("legacy Anomalies = %v, want the same message appended", f.Anomalies)
+	}
+}
+
+func TestAnomalyStringsMatchesRecordedMessages(t *testing.T) {
+	f := &File{Anomalies: []string{}}
+
+	f.addAnomalyStruct(Anomaly{ID: "A", Message: "first"})
+	f.addAnomalyStruct(Anomaly{ID: "B", Message: "second"})
+
+	got := f.AnomalyStrings()
+	want := []string{"first", "second"}
+	if len(got) != len(want) {
+		t.Fatalf("AnomalyStrings() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("AnomalyStrings()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	tests := []struct {
+		sev  Severity
+		want string
+	}{
+		{SeverityInfo, "info"},
+		{SeverityLow, "low"},
+		{SeverityMedium, "medium"},
+		{SeverityHigh, "high"},
+		{SeverityCritical, "critical"},
+	}
+	for _, tt := range tests {
+		if got := tt.sev.String(); got != tt.want {
+			t.Errorf("Severity(%d).String() = %s, want %s", tt.sev, got, tt.want)
+		}
+	}
+}
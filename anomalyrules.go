@@ -0,0 +1,64 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "github.com/saferwall/pe/anomaly/rules"
+
+// ruleTarget adapts *File to the rules.Target interface the anomaly rule
+// engine is evaluated against. anomaly/rules cannot import this package
+// directly (this package already imports anomaly/rules to run the engine,
+// and Go doesn't allow the cycle that importing back would create), so the
+// adapter lives here instead.
+type ruleTarget struct{ pe *File }
+
+func (t ruleTarget) Sections() []rules.SectionView {
+	out := make([]rules.SectionView, len(t.pe.Sections))
+	for i, s := range t.pe.Sections {
+		out[i] = rules.SectionView{Name: s.NameString(), Entropy: s.Entropy}
+	}
+	return out
+}
+
+func (t ruleTarget) Characteristics() uint32 {
+	return uint32(t.pe.NtHeader.FileHeader.Characteristics)
+}
+
+func (t ruleTarget) ImportCount() int { return len(t.pe.Imports) }
+
+func (t ruleTarget) ExportCount() int { return len(t.pe.Export.Functions) }
+
+func (t ruleTarget) Timestamp() uint32 { return t.pe.NtHeader.FileHeader.TimeDateStamp }
+
+// runAnomalyRules runs the struct-based detectors that don't yet have a
+// home of their own in GetAnomalies (data directory bounds, checksum
+// verification, overlay detection), then evaluates every registered rule
+// (built-in plus, if Options.AnomalyRulesPath is set, any user-supplied
+// rules loaded from a YAML/JSON file) against the file. Rule results and
+// the checksum and overlay-entropy anomalies are recorded as structured
+// Anomaly values via addAnomalyStruct, which also keeps GetAnomalies'
+// legacy []string output populated; the data directory anomalies and the
+// overlay-present anomaly are recorded through addAnomaly as plain strings
+// only.
+func (pe *File) runAnomalyRules() error {
+	pe.checkDataDirectoryAnomalies()
+	pe.checkChecksumAnomalies()
+	pe.checkOverlayAnomalies()
+
+	if pe.opts != nil && pe.opts.AnomalyRulesPath != "" {
+		if err := rules.LoadFile(pe.opts.AnomalyRulesPath); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range rules.Run(ruleTarget{pe: pe}) {
+		pe.addAnomalyStruct(Anomaly{
+			ID:       a.ID,
+			Category: AnomalyCategory(a.Category),
+			Severity: Severity(a.Severity),
+			Message:  a.Message,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestRunAnomalyRulesDetectsPackedSection(t *testing.T) {
+	entropy := 7.9
+	var hdr ImageSectionHeader
+	copy(hdr.Name[:], "UPX0")
+
+	file := &File{
+		Anomalies: []string{},
+		Sections:  []Section{{Header: hdr, Entropy: &entropy}},
+	}
+
+	if err := file.runAnomalyRules(); err != nil {
+		t.Fatalf("runAnomalyRules() error = %v", err)
+	}
+
+	if len(file.Anomalies) == 0 {
+		t.Error("expected at least one anomaly for a UPX0 section")
+	}
+}
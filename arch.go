@@ -4,35 +4,427 @@
 
 package pe
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DVRT fixup types, encoded in the top 4 bits of each dynamic value
+// relocation entry's 16-bit header (IMAGE_DVRT_ARM64X_FIXUP_TYPE).
+const (
+	ImageDvrtArm64xFixupTypeZeroFill = 0
+	ImageDvrtArm64xFixupTypeValue    = 1
+	ImageDvrtArm64xFixupTypeDelta    = 2
+)
+
+// DynamicValueRelocation represents a single decoded entry from the
+// ARM64X dynamic value relocation table (DVRT). PageRVA is the RVA of
+// the containing 4K page, and Offset is the low-12-bit offset of the
+// fixup within that page, so the fixup applies at PageRVA+Offset.
+type DynamicValueRelocation struct {
+	PageRVA uint32
+	Offset  uint16
+	Type    uint8
+	Size    uint8
+	Value   uint64 // inline replacement value, for ZeroFill/Value fixups.
+	Delta   int64  // signed delta, for Delta fixups.
+}
+
+// CodeRange describes one entry of the ARM64EC/ARM64X code range table,
+// marking a contiguous region of the image as either native ARM64 code or
+// emulated x64 code.
+type CodeRange struct {
+	StartRVA uint32
+	Length   uint32
+	Type     uint32
+}
+
+// ARM64XRedirectionEntry maps an x64 entry point RVA to the ARM64EC
+// thunk that the loader must call instead.
+type ARM64XRedirectionEntry struct {
+	Source uint32
+	Target uint32
+}
+
+// CompilerIATEntry is one slot of the ARM64EC auxiliary Import Address
+// Table (also called the "compiler IAT", since the compiler-generated x64
+// thunks consult it instead of the regular IAT): a parallel entry the
+// loader substitutes in the ARM64EC view wherever the x64 view's IAT holds
+// the import thunk at ImportRVA.
+type CompilerIATEntry struct {
+	ImportRVA uint32
+	Value     uint64
+}
+
+// CHPEMetadata is the fixed, version-tagged header of the CHPE metadata
+// structure (IMAGE_ARM64EC_METADATA / IMAGE_ARM64X_METADATA): everything
+// besides the code range table and compiler IAT, which HybridPE surfaces
+// as their own decoded slices.
+type CHPEMetadata struct {
+	Version uint32
+	// EntryThunks maps an x64 entry-point RVA to the ARM64EC thunk the
+	// loader must redirect calls to instead.
+	EntryThunks []ARM64XRedirectionEntry
+}
+
+// HybridPE holds the parsed contents of the CHPE metadata structure
+// reached through the load config directory's CHPEMetadataPointer, for
+// hybrid ARM64X/ARM64EC images. It is surfaced as File.HybridPE. The
+// dynamic value relocation table (DVRT) the loader applies to materialize
+// the alternate-architecture view is a separate structure, reached through
+// the load config directory's own DynamicValueRelocTableOffset/Section
+// rather than through this one, and is surfaced as File.DynamicRelocations.
+type HybridPE struct {
+	CHPEMetadata CHPEMetadata
+	// CodeRanges marks contiguous regions of the image as native ARM64 or
+	// emulated x64 code.
+	CodeRanges []CodeRange
+	// CompilerIAT is the auxiliary Import Address Table used by the
+	// ARM64EC view.
+	CompilerIAT []CompilerIATEntry
+}
+
+// dvrtBlockHeader is the per-block header preceding a run of
+// variable-length dynamic value relocation entries.
+type dvrtBlockHeader struct {
+	BaseRelocRVA uint32
+	BlockSize    uint32
+}
 
 // Architecture-specific data. This data directory is not used
 // (set to all zeros) for I386, IA64, or AMD64 architecture.
-// For hybrid PE files (ARM64X, ARM64EC), this may contain architecture metadata.
+// For hybrid PE files (ARM64X, ARM64EC), this parser reads the CHPE
+// metadata through the load config's CHPEMetadataPointer, and the dynamic
+// value relocation table (DVRT) through the load config's
+// DynamicValueRelocTableOffset/Section; rva and size only gate whether
+// parsing runs.
 func (pe *File) parseArchitectureDirectory(rva, size uint32) error {
-	// Skip parsing if directory is empty (common case)
+	// Skip parsing if directory is empty (common case).
 	if rva == 0 || size == 0 {
 		return nil
 	}
-	
-	// For hybrid PE files, architecture directory may contain:
-	// - Code integrity metadata for dual-architecture binaries
-	// - ARM64EC thunk information
-	// - Architecture-specific configuration data
-	
-	// Currently, the Microsoft PE specification doesn't define a standard
-	// structure for this directory. Implementation would depend on specific
-	// use cases and reverse engineering of existing ARM64X/ARM64EC binaries.
-	
-	// Log that architecture directory is present but not fully parsed
-	if pe.logger != nil {
-		pe.logger.Info("Architecture directory present but parsing not implemented", 
-			"rva", rva, "size", size, "machine", pe.NtHeader.FileHeader.Machine.String())
-	}
-	
-	// Mark as anomaly for further investigation
-	pe.Anomalies = append(pe.Anomalies, 
-		fmt.Sprintf("Architecture directory present at RVA 0x%x (size: %d bytes) - not fully parsed", rva, size))
-	
+
+	machine := pe.NtHeader.FileHeader.Machine
+	if machine != ImageFileMachineARM64X && machine != ImageFileMachineARM64EC {
+		pe.addAnomalyLogged("Architecture", rva, size, fmt.Sprintf(
+			"Architecture directory present at RVA 0x%x (size: %d bytes) on machine type %s - not fully parsed",
+			rva, size, machine.String()))
+		return nil
+	}
+
+	chpeRVA := pe.chpeMetadataPointer()
+	if chpeRVA == 0 {
+		pe.addAnomalyLogged("Architecture", rva, size, fmt.Sprintf(
+			"Architecture directory present at RVA 0x%x (size: %d bytes) but CHPEMetadataPointer is unset - not fully parsed",
+			rva, size))
+		return nil
+	}
+
+	meta, err := pe.parseARM64XMetadata(chpeRVA)
+	if err != nil {
+		pe.addAnomalyLogged("Architecture", rva, size, fmt.Sprintf(
+			"Architecture directory CHPE metadata at RVA 0x%x could not be parsed: %v", chpeRVA, err))
+		return nil
+	}
+	pe.HybridPE = meta
+
+	relocs, err := pe.getDynamicValueRelocTable()
+	if err != nil {
+		pe.addAnomaly(fmt.Sprintf("dynamic value relocation table could not be parsed: %v", err))
+	} else {
+		pe.DynamicRelocations = relocs
+	}
+
+	pe.logInfo("parsed ARM64X/ARM64EC architecture directory",
+		"rva", rva, "size", size, "machine", machine.String(),
+		"codeRanges", len(meta.CodeRanges),
+		"entryThunks", len(meta.CHPEMetadata.EntryThunks),
+		"relocations", len(pe.DynamicRelocations),
+		"compilerIAT", len(meta.CompilerIAT))
+
 	return nil
 }
+
+// chpeMetadataPointer returns the CHPEMetadataPointer field from whichever
+// load config directory variant was parsed, or 0 when unavailable.
+func (pe *File) chpeMetadataPointer() uint32 {
+	if pe.LoadConfig.Struct64.CHPEMetadataPointer != 0 {
+		return uint32(pe.LoadConfig.Struct64.CHPEMetadataPointer)
+	}
+	return pe.LoadConfig.Struct32.CHPEMetadataPointer
+}
+
+// parseARM64XMetadata reads the IMAGE_ARM64EC_METADATA/IMAGE_ARM64X_METADATA
+// structure at the given RVA and decodes its code-range table, redirection
+// metadata, and compiler IAT. The dynamic value relocation table is a
+// separate structure, not read through this RVA at all; see
+// getDynamicValueRelocTable.
+func (pe *File) parseARM64XMetadata(rva uint32) (HybridPE, error) {
+	var meta HybridPE
+
+	// IMAGE_ARM64EC_METADATA: version, code ranges, redirections, and an
+	// AuxiliaryIAT pointer. The exact field layout of this structure
+	// differs across toolchain releases and isn't available in this tree,
+	// so the offsets below are a best-effort reading of the publicly
+	// documented fields this parser cares about.
+	const metaHeaderSize = 4 * 7
+	data, err := pe.GetData(rva, metaHeaderSize)
+	if err != nil {
+		return meta, fmt.Errorf("reading CHPE metadata header: %w", err)
+	}
+
+	meta.CHPEMetadata.Version = binary.LittleEndian.Uint32(data[0:4])
+	codeRangeRVA := binary.LittleEndian.Uint32(data[4:8])
+	codeRangeCount := binary.LittleEndian.Uint32(data[8:12])
+	redirRVA := binary.LittleEndian.Uint32(data[12:16])
+	redirCount := binary.LittleEndian.Uint32(data[16:20])
+	auxIATRVA := binary.LittleEndian.Uint32(data[24:28])
+
+	if codeRangeRVA != 0 && codeRangeCount > 0 {
+		ranges, err := pe.parseARM64XCodeRanges(codeRangeRVA, codeRangeCount)
+		if err != nil {
+			pe.addAnomaly(fmt.Sprintf("ARM64X code range table at RVA 0x%x invalid: %v", codeRangeRVA, err))
+		} else {
+			meta.CodeRanges = ranges
+		}
+	}
+
+	if redirRVA != 0 && redirCount > 0 {
+		entries, err := pe.parseARM64XRedirections(redirRVA, redirCount)
+		if err != nil {
+			pe.addAnomaly(fmt.Sprintf("ARM64X redirection table at RVA 0x%x invalid: %v", redirRVA, err))
+		} else {
+			meta.CHPEMetadata.EntryThunks = entries
+		}
+	}
+
+	if auxIATRVA != 0 {
+		if iatDir, ok := pe.GetDataDirectory(int(ImageDirectoryEntryIATEntry)); ok && iatDir.Size > 0 {
+			entries, err := pe.parseCompilerIAT(auxIATRVA, iatDir.VirtualAddress, iatDir.Size)
+			if err != nil {
+				pe.addAnomaly(fmt.Sprintf("compiler IAT at RVA 0x%x invalid: %v", auxIATRVA, err))
+			} else {
+				meta.CompilerIAT = entries
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+// getDynamicValueRelocTable locates and decodes the ARM64X dynamic value
+// relocation table (DVRT) via the load config directory's own
+// DynamicValueRelocTableOffset and DynamicValueRelocTableSection: Section
+// is a 1-based index into the section table, and Offset is the byte offset
+// of the table within that section. This is how the loader itself finds
+// the DVRT; it is not reachable through the architecture directory's own
+// RVA, which points at CHPE metadata instead (see parseARM64XMetadata).
+//
+// The table begins with an 8-byte header (Version, Size) followed by Size
+// bytes of the same {BaseRelocRVA, BlockSize}-delimited blocks that
+// decodeDVRTBlocks already knows how to walk.
+func (pe *File) getDynamicValueRelocTable() ([]DynamicValueRelocation, error) {
+	dvrtOffset, dvrtSection := pe.dynamicValueRelocTableLocation()
+	if dvrtOffset == 0 || dvrtSection == 0 {
+		return nil, nil
+	}
+
+	sectionIndex := int(dvrtSection) - 1
+	if sectionIndex < 0 || sectionIndex >= len(pe.Sections) {
+		return nil, fmt.Errorf("DynamicValueRelocTableSection %d out of range (%d sections)", dvrtSection, len(pe.Sections))
+	}
+
+	rva := pe.Sections[sectionIndex].Header.VirtualAddress + dvrtOffset
+
+	hdr, err := pe.GetData(rva, 8)
+	if err != nil {
+		return nil, fmt.Errorf("reading DVRT header: %w", err)
+	}
+	tableSize := binary.LittleEndian.Uint32(hdr[4:8])
+
+	return pe.parseDynamicValueRelocTable(rva+8, tableSize)
+}
+
+// dynamicValueRelocTableLocation returns the DynamicValueRelocTableOffset
+// and DynamicValueRelocTableSection fields from whichever load config
+// directory variant was parsed, or zeros when unavailable.
+func (pe *File) dynamicValueRelocTableLocation() (offset uint32, section uint16) {
+	if pe.LoadConfig.Struct64.Size != 0 {
+		s := pe.LoadConfig.Struct64
+		return s.DynamicValueRelocTableOffset, s.DynamicValueRelocTableSection
+	}
+	s := pe.LoadConfig.Struct32
+	return s.DynamicValueRelocTableOffset, s.DynamicValueRelocTableSection
+}
+
+// parseCompilerIAT reads the ARM64EC compiler IAT at auxIATRVA, which
+// mirrors the regular Import Address Table (importRVA, importSize) one
+// pointer-sized slot at a time, pairing each auxiliary slot with the
+// import RVA it substitutes a value for.
+func (pe *File) parseCompilerIAT(auxIATRVA, importRVA, importSize uint32) ([]CompilerIATEntry, error) {
+	const slotSize = 8
+	count := importSize / slotSize
+	data, err := pe.GetData(auxIATRVA, count*slotSize)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCompilerIAT(data, importRVA), nil
+}
+
+// decodeCompilerIAT is the pure decoder behind parseCompilerIAT: it pairs
+// each pointer-sized slot of the already-resolved compiler IAT bytes with
+// the import RVA it substitutes a value for, without touching the File so
+// it can be exercised directly in tests.
+func decodeCompilerIAT(data []byte, importRVA uint32) []CompilerIATEntry {
+	const slotSize = 8
+	count := uint32(len(data)) / slotSize
+	entries := make([]CompilerIATEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		off := i * slotSize
+		entries = append(entries, CompilerIATEntry{
+			ImportRVA: importRVA + off,
+			Value:     binary.LittleEndian.Uint64(data[off : off+8]),
+		})
+	}
+	return entries
+}
+
+func (pe *File) parseARM64XCodeRanges(rva, count uint32) ([]CodeRange, error) {
+	const entrySize = 12
+	data, err := pe.GetData(rva, count*entrySize)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]CodeRange, 0, count)
+	for i := uint32(0); i < count; i++ {
+		off := i * entrySize
+		ranges = append(ranges, CodeRange{
+			StartRVA: binary.LittleEndian.Uint32(data[off : off+4]),
+			Length:   binary.LittleEndian.Uint32(data[off+4 : off+8]),
+			Type:     binary.LittleEndian.Uint32(data[off+8 : off+12]),
+		})
+	}
+	return ranges, nil
+}
+
+func (pe *File) parseARM64XRedirections(rva, count uint32) ([]ARM64XRedirectionEntry, error) {
+	const entrySize = 8
+	data, err := pe.GetData(rva, count*entrySize)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ARM64XRedirectionEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		off := i * entrySize
+		entries = append(entries, ARM64XRedirectionEntry{
+			Source: binary.LittleEndian.Uint32(data[off : off+4]),
+			Target: binary.LittleEndian.Uint32(data[off+4 : off+8]),
+		})
+	}
+	return entries, nil
+}
+
+// parseDynamicValueRelocTable walks the DVRT: a sequence of blocks, each a
+// {BaseRelocRVA, BlockSize} header followed by variable-length entries
+// whose top 4 bits encode the fixup type and whose low 12 bits encode the
+// in-page offset. Iteration stops once size bytes have been consumed.
+func (pe *File) parseDynamicValueRelocTable(rva, size uint32) ([]DynamicValueRelocation, error) {
+	data, err := pe.GetData(rva, size)
+	if err != nil {
+		return nil, fmt.Errorf("reading DVRT: %w", err)
+	}
+
+	relocs, anomalies := decodeDVRTBlocks(data)
+	for _, a := range anomalies {
+		pe.addAnomaly(a)
+	}
+	return relocs, nil
+}
+
+// decodeDVRTBlocks is the pure decoder behind parseDynamicValueRelocTable: it
+// takes the raw DVRT bytes (already resolved via the load config's DVRT
+// section and offset) and
+// returns the decoded entries plus any validation anomalies encountered,
+// without touching the File so it can be exercised directly in tests.
+func decodeDVRTBlocks(data []byte) ([]DynamicValueRelocation, []string) {
+	size := uint32(len(data))
+
+	var relocs []DynamicValueRelocation
+	var anomalies []string
+	var consumed uint32
+	for consumed+8 <= size {
+		var hdr dvrtBlockHeader
+		hdr.BaseRelocRVA = binary.LittleEndian.Uint32(data[consumed : consumed+4])
+		hdr.BlockSize = binary.LittleEndian.Uint32(data[consumed+4 : consumed+8])
+
+		if hdr.BlockSize < 8 || consumed+hdr.BlockSize > size {
+			anomalies = append(anomalies, fmt.Sprintf(
+				"DVRT block at offset %d declares size %d exceeding table bounds", consumed, hdr.BlockSize))
+			break
+		}
+
+		entryEnd := consumed + hdr.BlockSize
+		cursor := consumed + 8
+		for cursor+2 <= entryEnd {
+			raw := binary.LittleEndian.Uint16(data[cursor : cursor+2])
+			cursor += 2
+
+			fixupType := uint8(raw >> 12)
+			pageOffset := raw & 0x0FFF
+
+			reloc := DynamicValueRelocation{
+				PageRVA: hdr.BaseRelocRVA,
+				Offset:  pageOffset,
+				Type:    fixupType,
+			}
+
+			switch fixupType {
+			case ImageDvrtArm64xFixupTypeZeroFill:
+				// Size is encoded in bits 8-9 of the low 12 bits (1,2,4,8 bytes).
+				reloc.Size = 1 << ((pageOffset >> 8) & 0x3)
+			case ImageDvrtArm64xFixupTypeValue:
+				valueSize := 1 << ((pageOffset >> 8) & 0x3)
+				reloc.Size = uint8(valueSize)
+				if cursor+uint32(valueSize) > entryEnd {
+					anomalies = append(anomalies, fmt.Sprintf(
+						"DVRT VALUE fixup at page 0x%x overruns block", hdr.BaseRelocRVA))
+					cursor = entryEnd
+					continue
+				}
+				var v uint64
+				for b := 0; b < valueSize; b++ {
+					v |= uint64(data[cursor+uint32(b)]) << (8 * b)
+				}
+				reloc.Value = v
+				cursor += uint32(valueSize)
+			case ImageDvrtArm64xFixupTypeDelta:
+				reloc.Size = 4
+				if (pageOffset>>8)&0x1 != 0 {
+					reloc.Size = 8
+				}
+				if cursor+4 > entryEnd {
+					anomalies = append(anomalies, fmt.Sprintf(
+						"DVRT DELTA fixup at page 0x%x overruns block", hdr.BaseRelocRVA))
+					cursor = entryEnd
+					continue
+				}
+				raw32 := int32(binary.LittleEndian.Uint32(data[cursor : cursor+4]))
+				scale := int64(1)
+				if reloc.Size == 8 {
+					scale = 2
+				}
+				reloc.Delta = int64(raw32) * scale
+				cursor += 4
+			default:
+				anomalies = append(anomalies, fmt.Sprintf(
+					"DVRT entry at page 0x%x uses unknown fixup type %d", hdr.BaseRelocRVA, fixupType))
+			}
+
+			relocs = append(relocs, reloc)
+		}
+
+		consumed += hdr.BlockSize
+	}
+
+	return relocs, anomalies
+}
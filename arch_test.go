@@ -5,6 +5,7 @@
 package pe
 
 import (
+	"encoding/binary"
 	"strings"
 	"testing"
 )
@@ -179,4 +180,226 @@ func (m *mockLogger) Debug(msg string, keyvals ...interface{}) {
 func (m *mockLogger) Warn(msg string, keyvals ...interface{}) {
 	m.lastMessage = msg
 	m.lastLevel = "warn"
-}
\ No newline at end of file
+}
+
+// buildDVRTBlock encodes one DVRT block header followed by raw entry bytes.
+func buildDVRTBlock(baseRelocRVA uint32, entries []byte) []byte {
+	block := make([]byte, 8+len(entries))
+	binary.LittleEndian.PutUint32(block[0:4], baseRelocRVA)
+	binary.LittleEndian.PutUint32(block[4:8], uint32(len(block)))
+	copy(block[8:], entries)
+	return block
+}
+
+func TestDecodeDVRTBlocksZeroFill(t *testing.T) {
+	// ZeroFill entry: type=0 in top nibble, size index 0b01 (2 bytes) in
+	// bits 8-9, page offset 0x010.
+	entry := make([]byte, 2)
+	binary.LittleEndian.PutUint16(entry, uint16(0x010)|(0b01<<8))
+	data := buildDVRTBlock(0x2000, entry)
+
+	relocs, anomalies := decodeDVRTBlocks(data)
+	if len(anomalies) != 0 {
+		t.Fatalf("unexpected anomalies: %v", anomalies)
+	}
+	if len(relocs) != 1 {
+		t.Fatalf("expected 1 reloc, got %d", len(relocs))
+	}
+	got := relocs[0]
+	if got.PageRVA != 0x2000 || got.Type != ImageDvrtArm64xFixupTypeZeroFill || got.Size != 2 {
+		t.Errorf("unexpected reloc: %+v", got)
+	}
+}
+
+func TestDecodeDVRTBlocksValue(t *testing.T) {
+	// Value entry: type=1, size index 0b10 (4 bytes), inline payload 0xAABBCCDD.
+	entry := make([]byte, 6)
+	binary.LittleEndian.PutUint16(entry[0:2], uint16(0x020)|(0b10<<8)|(ImageDvrtArm64xFixupTypeValue<<12))
+	binary.LittleEndian.PutUint32(entry[2:6], 0xAABBCCDD)
+	data := buildDVRTBlock(0x3000, entry)
+
+	relocs, anomalies := decodeDVRTBlocks(data)
+	if len(anomalies) != 0 {
+		t.Fatalf("unexpected anomalies: %v", anomalies)
+	}
+	if len(relocs) != 1 {
+		t.Fatalf("expected 1 reloc, got %d", len(relocs))
+	}
+	got := relocs[0]
+	if got.Size != 4 || got.Value != 0xAABBCCDD {
+		t.Errorf("unexpected reloc: %+v", got)
+	}
+}
+
+func TestDecodeDVRTBlocksDelta(t *testing.T) {
+	// Delta entry: type=2, 8-byte field flag set, delta -4 (scaled by 2 -> -8).
+	entry := make([]byte, 6)
+	binary.LittleEndian.PutUint16(entry[0:2], uint16(0x030)|(0b1<<8)|(ImageDvrtArm64xFixupTypeDelta<<12))
+	binary.LittleEndian.PutUint32(entry[2:6], uint32(int32(-4)))
+	data := buildDVRTBlock(0x4000, entry)
+
+	relocs, anomalies := decodeDVRTBlocks(data)
+	if len(anomalies) != 0 {
+		t.Fatalf("unexpected anomalies: %v", anomalies)
+	}
+	if len(relocs) != 1 {
+		t.Fatalf("expected 1 reloc, got %d", len(relocs))
+	}
+	got := relocs[0]
+	if got.Size != 8 || got.Delta != -8 {
+		t.Errorf("unexpected reloc: %+v", got)
+	}
+}
+
+func TestDecodeDVRTBlocksUnknownFixupType(t *testing.T) {
+	entry := make([]byte, 2)
+	binary.LittleEndian.PutUint16(entry, uint16(0x040)|(0xF<<12))
+	data := buildDVRTBlock(0x5000, entry)
+
+	_, anomalies := decodeDVRTBlocks(data)
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly for unknown fixup type, got %d: %v", len(anomalies), anomalies)
+	}
+	if !strings.Contains(anomalies[0], "unknown fixup type") {
+		t.Errorf("anomaly should mention unknown fixup type, got: %s", anomalies[0])
+	}
+}
+
+func TestDecodeDVRTBlocksOverrunBlockSize(t *testing.T) {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[0:4], 0x6000)
+	binary.LittleEndian.PutUint32(data[4:8], 0x1000) // declares far more than available
+
+	relocs, anomalies := decodeDVRTBlocks(data)
+	if len(relocs) != 0 {
+		t.Errorf("expected no relocs decoded from a truncated block, got %d", len(relocs))
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly for oversized block, got %d: %v", len(anomalies), anomalies)
+	}
+	if !strings.Contains(anomalies[0], "exceeding table bounds") {
+		t.Errorf("anomaly should mention table bounds, got: %s", anomalies[0])
+	}
+}
+func TestDecodeCompilerIAT(t *testing.T) {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint64(data[0:8], 0xDEADBEEF)
+	binary.LittleEndian.PutUint64(data[8:16], 0xCAFEBABE)
+
+	entries := decodeCompilerIAT(data, 0x3000)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ImportRVA != 0x3000 || entries[0].Value != 0xDEADBEEF {
+		t.Errorf("entry 0 = %+v", entries[0])
+	}
+	if entries[1].ImportRVA != 0x3008 || entries[1].Value != 0xCAFEBABE {
+		t.Errorf("entry 1 = %+v", entries[1])
+	}
+}
+
+func TestDecodeCompilerIATEmpty(t *testing.T) {
+	if entries := decodeCompilerIAT(nil, 0x1000); len(entries) != 0 {
+		t.Errorf("expected no entries for empty data, got %d", len(entries))
+	}
+}
+
+// TestParseARM64XMetadataRealPayload builds a real CHPE metadata byte blob
+// (version, one code range, one redirection entry, no compiler IAT) and
+// verifies parseARM64XMetadata decodes it into a populated HybridPE,
+// rather than only exercising the graceful-failure paths above.
+func TestParseARM64XMetadataRealPayload(t *testing.T) {
+	const (
+		metaRVA      = 0x4000
+		codeRangeRVA = 0x4100
+		redirRVA     = 0x4200
+	)
+
+	meta := make([]byte, 28)
+	binary.LittleEndian.PutUint32(meta[0:4], 1) // Version
+	binary.LittleEndian.PutUint32(meta[4:8], codeRangeRVA)
+	binary.LittleEndian.PutUint32(meta[8:12], 1) // codeRangeCount
+	binary.LittleEndian.PutUint32(meta[12:16], redirRVA)
+	binary.LittleEndian.PutUint32(meta[16:20], 1) // redirCount
+	binary.LittleEndian.PutUint32(meta[24:28], 0) // no compiler IAT
+
+	codeRange := make([]byte, 12)
+	binary.LittleEndian.PutUint32(codeRange[0:4], 0x1000)
+	binary.LittleEndian.PutUint32(codeRange[4:8], 0x200)
+	binary.LittleEndian.PutUint32(codeRange[8:12], arm64XCodeRangeTypeARM64)
+
+	redir := make([]byte, 8)
+	binary.LittleEndian.PutUint32(redir[0:4], 0x5000)
+	binary.LittleEndian.PutUint32(redir[4:8], 0x5100)
+
+	data := make([]byte, 0x4300)
+	copy(data[metaRVA:], meta)
+	copy(data[codeRangeRVA:], codeRange)
+	copy(data[redirRVA:], redir)
+
+	f := &File{
+		Anomalies: []string{},
+		data:      data,
+		Sections: []Section{
+			{Header: ImageSectionHeader{VirtualAddress: 0, VirtualSize: 0x4300}},
+		},
+	}
+
+	got, err := f.parseARM64XMetadata(metaRVA)
+	if err != nil {
+		t.Fatalf("parseARM64XMetadata() error = %v", err)
+	}
+	if got.CHPEMetadata.Version != 1 {
+		t.Errorf("Version = %d, want 1", got.CHPEMetadata.Version)
+	}
+	if len(got.CodeRanges) != 1 || got.CodeRanges[0].StartRVA != 0x1000 || got.CodeRanges[0].Length != 0x200 {
+		t.Errorf("CodeRanges = %+v", got.CodeRanges)
+	}
+	if len(got.CHPEMetadata.EntryThunks) != 1 || got.CHPEMetadata.EntryThunks[0].Source != 0x5000 {
+		t.Errorf("EntryThunks = %+v", got.CHPEMetadata.EntryThunks)
+	}
+}
+
+// TestGetDynamicValueRelocTableResolvesViaLoadConfig builds a DVRT reached
+// through LoadConfig.Struct64's DynamicValueRelocTableOffset/Section
+// (resolved against a section's VirtualAddress), not through any offset
+// guessed from the CHPE metadata header, and verifies it decodes.
+func TestGetDynamicValueRelocTableResolvesViaLoadConfig(t *testing.T) {
+	entry := make([]byte, 2)
+	binary.LittleEndian.PutUint16(entry, uint16(0x010)|(0b01<<8))
+	block := buildDVRTBlock(0x2000, entry)
+
+	const sectionVA = 0x3000
+	const dvrtOffsetInSection = 0x40
+
+	table := make([]byte, 8+len(block))
+	binary.LittleEndian.PutUint32(table[0:4], 1)                  // Version
+	binary.LittleEndian.PutUint32(table[4:8], uint32(len(block))) // Size
+	copy(table[8:], block)
+
+	data := make([]byte, sectionVA+dvrtOffsetInSection+len(table))
+	copy(data[sectionVA+dvrtOffsetInSection:], table)
+
+	f := &File{
+		Anomalies: []string{},
+		data:      data,
+		Sections: []Section{
+			{Header: ImageSectionHeader{VirtualAddress: sectionVA, VirtualSize: uint32(len(data) - sectionVA)}},
+		},
+		LoadConfig: LoadConfigDirectory{
+			Struct64: ImageLoadConfigDirectory64{
+				Size:                          1,
+				DynamicValueRelocTableOffset:  dvrtOffsetInSection,
+				DynamicValueRelocTableSection: 1,
+			},
+		},
+	}
+
+	relocs, err := f.getDynamicValueRelocTable()
+	if err != nil {
+		t.Fatalf("getDynamicValueRelocTable() error = %v", err)
+	}
+	if len(relocs) != 1 || relocs[0].PageRVA != 0x2000 {
+		t.Errorf("relocs = %+v", relocs)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotHybridImage is returned by ARM64XAlternate when the file's machine
+// type isn't ARM64X/ARM64EC, so there is no alternate-architecture view to
+// materialize.
+var ErrNotHybridImage = errors.New("pe: not an ARM64X/ARM64EC hybrid image")
+
+// ErrARM64XMetadataNotParsed is returned by ARM64XAlternate when the
+// architecture directory hasn't been parsed, so no DVRT fixups are known.
+var ErrARM64XMetadataNotParsed = errors.New("pe: ARM64X metadata not parsed (parse the architecture directory first)")
+
+// ARM64XAlternate materializes the second logical image overlaid inside an
+// ARM64X binary: it takes a copy of the raw file bytes, applies every
+// decoded dynamic value relocation table (DVRT) fixup from
+// pe.DynamicRelocations in the same way the Windows loader does to produce
+// the alternate architecture's view, and re-parses the patched bytes as an
+// independent *File. The result's NT header, section table, and
+// directories (imports, exports, base relocations, ...) reflect the
+// alternate architecture, so callers can inspect both halves of the hybrid
+// image through the same API.
+func (pe *File) ARM64XAlternate() (*File, error) {
+	machine := pe.NtHeader.FileHeader.Machine
+	if machine != ImageFileMachineARM64X && machine != ImageFileMachineARM64EC {
+		return nil, ErrNotHybridImage
+	}
+	if pe.DynamicRelocations == nil {
+		return nil, ErrARM64XMetadataNotParsed
+	}
+
+	raw := pe.RawData()
+	patched := make([]byte, len(raw))
+	copy(patched, raw)
+
+	for _, reloc := range pe.DynamicRelocations {
+		rva := reloc.PageRVA + uint32(reloc.Offset)
+		offset := pe.GetOffsetFromRva(rva)
+		if offset == 0 && rva != 0 {
+			continue
+		}
+		if err := applyARM64XFixup(patched, int64(offset), reloc); err != nil {
+			pe.addAnomaly(fmt.Sprintf(
+				"ARM64X fixup at RVA 0x%x could not be applied: %v", rva, err))
+		}
+	}
+
+	alt, err := NewBytes(patched, &Options{})
+	if err != nil {
+		return nil, fmt.Errorf("parsing fixed-up ARM64X alternate image: %w", err)
+	}
+	return alt, nil
+}
+
+// applyARM64XFixup writes a single decoded DVRT entry into data at offset,
+// mirroring how the loader materializes the alternate-architecture view:
+// zero-fill clears reloc.Size bytes, value fixups overwrite them with the
+// decoded literal payload, and delta fixups add the signed delta to
+// whatever 4- or 8-byte value is already there.
+func applyARM64XFixup(data []byte, offset int64, reloc DynamicValueRelocation) error {
+	if offset < 0 {
+		return fmt.Errorf("negative offset")
+	}
+
+	switch reloc.Type {
+	case ImageDvrtArm64xFixupTypeZeroFill:
+		end := offset + int64(reloc.Size)
+		if end > int64(len(data)) {
+			return fmt.Errorf("zero-fill of %d bytes at offset 0x%x out of bounds", reloc.Size, offset)
+		}
+		for i := offset; i < end; i++ {
+			data[i] = 0
+		}
+		return nil
+
+	case ImageDvrtArm64xFixupTypeValue:
+		end := offset + int64(reloc.Size)
+		if end > int64(len(data)) {
+			return fmt.Errorf("value fixup of %d bytes at offset 0x%x out of bounds", reloc.Size, offset)
+		}
+		for i := 0; i < int(reloc.Size); i++ {
+			data[offset+int64(i)] = byte(reloc.Value >> (8 * i))
+		}
+		return nil
+
+	case ImageDvrtArm64xFixupTypeDelta:
+		end := offset + int64(reloc.Size)
+		if end > int64(len(data)) {
+			return fmt.Errorf("delta fixup of %d bytes at offset 0x%x out of bounds", reloc.Size, offset)
+		}
+		var original uint64
+		for i := 0; i < int(reloc.Size); i++ {
+			original |= uint64(data[offset+int64(i)]) << (8 * i)
+		}
+		updated := uint64(int64(original) + reloc.Delta)
+		for i := 0; i < int(reloc.Size); i++ {
+			data[offset+int64(i)] = byte(updated >> (8 * i))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown fixup type %d", reloc.Type)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestApplyARM64XFixupZeroFill(t *testing.T) {
+	data := []byte{0xAA, 0xBB, 0xCC, 0xDD}
+	reloc := DynamicValueRelocation{Type: ImageDvrtArm64xFixupTypeZeroFill, Size: 2}
+
+	if err := applyARM64XFixup(data, 1, reloc); err != nil {
+		t.Fatalf("applyARM64XFixup() error = %v", err)
+	}
+	want := []byte{0xAA, 0x00, 0x00, 0xDD}
+	if string(data) != string(want) {
+		t.Errorf("data = % x, want % x", data, want)
+	}
+}
+
+func TestApplyARM64XFixupValue(t *testing.T) {
+	data := make([]byte, 4)
+	reloc := DynamicValueRelocation{Type: ImageDvrtArm64xFixupTypeValue, Size: 4, Value: 0xAABBCCDD}
+
+	if err := applyARM64XFixup(data, 0, reloc); err != nil {
+		t.Fatalf("applyARM64XFixup() error = %v", err)
+	}
+	want := []byte{0xDD, 0xCC, 0xBB, 0xAA}
+	if string(data) != string(want) {
+		t.Errorf("data = % x, want % x", data, want)
+	}
+}
+
+func TestApplyARM64XFixupDelta(t *testing.T) {
+	data := []byte{0x10, 0x00, 0x00, 0x00}
+	reloc := DynamicValueRelocation{Type: ImageDvrtArm64xFixupTypeDelta, Size: 4, Delta: 0x20}
+
+	if err := applyARM64XFixup(data, 0, reloc); err != nil {
+		t.Fatalf("applyARM64XFixup() error = %v", err)
+	}
+	want := []byte{0x30, 0x00, 0x00, 0x00}
+	if string(data) != string(want) {
+		t.Errorf("data = % x, want % x", data, want)
+	}
+}
+
+func TestApplyARM64XFixupOutOfBounds(t *testing.T) {
+	data := make([]byte, 2)
+	reloc := DynamicValueRelocation{Type: ImageDvrtArm64xFixupTypeValue, Size: 4, Value: 1}
+
+	if err := applyARM64XFixup(data, 0, reloc); err == nil {
+		t.Error("expected an out-of-bounds error, got nil")
+	}
+}
+
+func TestApplyARM64XFixupUnknownType(t *testing.T) {
+	data := make([]byte, 4)
+	reloc := DynamicValueRelocation{Type: 0xF, Size: 4}
+
+	if err := applyARM64XFixup(data, 0, reloc); err == nil {
+		t.Error("expected an error for an unknown fixup type, got nil")
+	}
+}
+
+func TestARM64XAlternateRejectsNonHybridMachine(t *testing.T) {
+	f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineAMD64}}}
+
+	if _, err := f.ARM64XAlternate(); err != ErrNotHybridImage {
+		t.Errorf("ARM64XAlternate() error = %v, want %v", err, ErrNotHybridImage)
+	}
+}
+
+func TestARM64XAlternateRequiresParsedMetadata(t *testing.T) {
+	f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineARM64X}}}
+
+	if _, err := f.ARM64XAlternate(); err != ErrARM64XMetadataNotParsed {
+		t.Errorf("ARM64XAlternate() error = %v, want %v", err, ErrARM64XMetadataNotParsed)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGoBuildInfoNotFound is returned by GoBuildInfo when no section of the
+// image contains the Go buildinfo magic.
+var ErrGoBuildInfoNotFound = errors.New("pe: Go buildinfo magic not found")
+
+// ErrGoBuildInfoTruncated is returned by GoBuildInfo when the magic is
+// present but the payload it points to is truncated or its pointers dangle
+// outside any section.
+var ErrGoBuildInfoTruncated = errors.New("pe: Go buildinfo payload is truncated or invalid")
+
+// goBuildInfoMagic is the 14-byte sentinel the Go linker writes at the start
+// of the buildinfo blob embedded in every Go >= 1.13 binary.
+var goBuildInfoMagic = []byte("\xff Go buildinf:")
+
+// goModInfoTag brackets the module info blob (runtime.modinfo) the linker
+// embeds for Go >= 1.18 binaries, so it can be located without a symbol
+// table.
+var goModInfoTag = []byte("\xf8\x30\x77\xaf\x0c\x92\x74\x08\x41\xe1\xc1\x07\xe6\xd6\x18\xe6")[1:]
+
+// Module describes one entry of the Go module graph embedded in a binary's
+// build info: the main module, or one of its dependencies.
+type Module struct {
+	Path    string
+	Version string
+	Sum     string
+	Replace *Module
+}
+
+// BuildSetting is a single `go build -ldflags`/VCS/toolchain key-value pair
+// recorded by the Go linker, e.g. {"GOARCH", "arm64"} or {"vcs.revision", "..."}.
+type BuildSetting struct {
+	Key   string
+	Value string
+}
+
+// GoBuildInfo is the parsed contents of the `.go.buildinfo` blob that the Go
+// linker embeds in every binary built with Go 1.13+, mirroring the shape of
+// `debug/buildinfo.BuildInfo` in the Go standard library.
+type GoBuildInfo struct {
+	GoVersion string
+	Path      string
+	Main      Module
+	Deps      []Module
+	Settings  []BuildSetting
+}
+
+// HasGoBuildInfo reports whether the image contains a recognizable Go
+// buildinfo blob, without fully parsing it.
+func (pe *File) HasGoBuildInfo() bool {
+	return pe.goBuildInfoOffset() >= 0
+}
+
+// GoBuildInfo locates and parses the embedded Go module build info, in the
+// same spirit as `golang.org/x/tools` and `syft`'s Go binary cataloger: find
+// the buildinfo magic (scanning `.go.buildinfo` first, then every section as
+// a fallback for stripped/renamed sections), read the pointer size and
+// endianness byte, then either decode the inline varint-length-prefixed
+// strings (Go >= 1.18, flag 0x2) or follow the two legacy pointer fields to
+// runtime.buildVersion/runtime.modinfo.
+func (pe *File) GoBuildInfo() (GoBuildInfo, error) {
+	var info GoBuildInfo
+
+	off := pe.goBuildInfoOffset()
+	if off < 0 {
+		return info, ErrGoBuildInfoNotFound
+	}
+
+	data, err := pe.dataAtOffset(off, 64)
+	if err != nil {
+		return info, fmt.Errorf("reading buildinfo header: %w", err)
+	}
+
+	ptrSize := data[14]
+	if ptrSize != 4 && ptrSize != 8 {
+		pe.addAnomaly("Go buildinfo magic found but pointer size byte is invalid")
+		return info, ErrGoBuildInfoTruncated
+	}
+
+	flags := data[15]
+	bigEndian := flags&0x1 != 0
+	order := binary.ByteOrder(binary.LittleEndian)
+	if bigEndian {
+		order = binary.BigEndian
+	}
+
+	if flags&0x2 != 0 {
+		// Go >= 1.18: the version and module info strings follow inline,
+		// each length-prefixed with a varint.
+		raw := pe.RawData()
+		version, rest, err := readGoBuildInfoString(raw[off+32:])
+		if err != nil {
+			pe.addAnomaly("Go buildinfo inline version string is truncated")
+			return info, ErrGoBuildInfoTruncated
+		}
+		modInfo, _, err := readGoBuildInfoString(rest)
+		if err != nil {
+			pe.addAnomaly("Go buildinfo inline module info string is truncated")
+			return info, ErrGoBuildInfoTruncated
+		}
+		info.GoVersion = version
+		return pe.finishGoBuildInfo(info, modInfo)
+	}
+
+	// Legacy (Go 1.13-1.17): two pointers to runtime.buildVersion and
+	// runtime.modinfo, sized/ordered per ptrSize/bigEndian.
+	ptr1, ptr2 := data[16:16+ptrSize], data[16+ptrSize:16+2*ptrSize]
+	versionAddr := readUintPtr(order, ptr1)
+	modInfoAddr := readUintPtr(order, ptr2)
+
+	version, err := pe.readGoString(versionAddr)
+	if err != nil {
+		pe.addAnomaly(fmt.Sprintf("Go buildinfo version pointer 0x%x is dangling", versionAddr))
+		return info, ErrGoBuildInfoTruncated
+	}
+	modInfo, err := pe.readGoString(modInfoAddr)
+	if err != nil {
+		pe.addAnomaly(fmt.Sprintf("Go buildinfo modinfo pointer 0x%x is dangling", modInfoAddr))
+		return info, ErrGoBuildInfoTruncated
+	}
+
+	info.GoVersion = version
+	return pe.finishGoBuildInfo(info, modInfo)
+}
+
+// finishGoBuildInfo strips the modinfo sentinel and parses the module graph.
+func (pe *File) finishGoBuildInfo(info GoBuildInfo, modInfo string) (GoBuildInfo, error) {
+	modInfo = strings.TrimPrefix(modInfo, string(goModInfoTag))
+	modInfo = strings.TrimSuffix(modInfo, string(goModInfoTag))
+	modInfo = strings.TrimSpace(modInfo)
+	if modInfo == "" {
+		return info, nil
+	}
+	parseGoModInfo(modInfo, &info)
+	return info, nil
+}
+
+// parseGoModInfo decodes the tab-separated `path`/`mod`/`dep`/`=>`/`build`
+// lines the Go linker writes into runtime.modinfo.
+func parseGoModInfo(modInfo string, info *GoBuildInfo) {
+	var last *Module
+	for _, line := range strings.Split(modInfo, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "path":
+			if len(fields) > 1 {
+				info.Path = fields[1]
+			}
+		case "mod":
+			if len(fields) > 3 {
+				info.Main = Module{Path: fields[1], Version: fields[2], Sum: fields[3]}
+				last = &info.Main
+			}
+		case "dep":
+			if len(fields) > 2 {
+				info.Deps = append(info.Deps, Module{Path: fields[1], Version: fields[2]})
+				last = &info.Deps[len(info.Deps)-1]
+				if len(fields) > 3 {
+					last.Sum = fields[3]
+				}
+			}
+		case "=>":
+			if len(fields) > 2 && last != nil {
+				last.Replace = &Module{Path: fields[1], Version: fields[2]}
+				if len(fields) > 3 {
+					last.Replace.Sum = fields[3]
+				}
+			}
+		case "build":
+			if len(fields) > 2 {
+				info.Settings = append(info.Settings, BuildSetting{Key: fields[1], Value: fields[2]})
+			}
+		}
+	}
+}
+
+// goBuildInfoOffset returns the file offset of the Go buildinfo magic,
+// preferring a `.go.buildinfo` section when present and otherwise scanning
+// every section, or -1 when the magic is absent.
+func (pe *File) goBuildInfoOffset() int64 {
+	for _, sec := range pe.Sections {
+		if !strings.EqualFold(sec.NameString(), ".go.buildinfo") {
+			continue
+		}
+		if off := pe.findMagicInSection(sec, goBuildInfoMagic); off >= 0 {
+			return off
+		}
+	}
+	for _, sec := range pe.Sections {
+		if strings.EqualFold(sec.NameString(), ".go.buildinfo") {
+			continue
+		}
+		if off := pe.findMagicInSection(sec, goBuildInfoMagic); off >= 0 {
+			return off
+		}
+	}
+	return -1
+}
+
+func readGoBuildInfoString(b []byte) (string, []byte, error) {
+	length, n := binary.Uvarint(b)
+	if n <= 0 || uint64(n)+length > uint64(len(b)) {
+		return "", nil, ErrGoBuildInfoTruncated
+	}
+	return string(b[n : n+int(length)]), b[n+int(length):], nil
+}
+
+func readUintPtr(order binary.ByteOrder, b []byte) uint64 {
+	if len(b) == 4 {
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
+
+// findMagicInSection reports the absolute file offset of magic within sec's
+// raw data, or -1 if not found.
+func (pe *File) findMagicInSection(sec Section, magic []byte) int64 {
+	raw := pe.RawData()
+	start := int64(sec.Header.PointerToRawData)
+	end := start + int64(sec.Header.SizeOfRawData)
+	if start >= int64(len(raw)) {
+		return -1
+	}
+	if end > int64(len(raw)) {
+		end = int64(len(raw))
+	}
+	idx := bytes.Index(raw[start:end], magic)
+	if idx < 0 {
+		return -1
+	}
+	return start + int64(idx)
+}
+
+// dataAtOffset reads n bytes at the given absolute file offset.
+func (pe *File) dataAtOffset(offset int64, n int) ([]byte, error) {
+	raw := pe.RawData()
+	if offset < 0 || offset+int64(n) > int64(len(raw)) {
+		return nil, fmt.Errorf("offset 0x%x+%d out of range", offset, n)
+	}
+	return raw[offset : offset+int64(n)], nil
+}
+
+// readGoString resolves a runtime string header's data pointer (given as a
+// virtual address, not an RVA) to the NUL-free byte slice through the PE's
+// section table, via the existing RVA helpers.
+func (pe *File) readGoString(addr uint64) (string, error) {
+	if addr == 0 {
+		return "", fmt.Errorf("null pointer")
+	}
+	rva := uint32(addr - pe.imageBase())
+	off := pe.GetOffsetFromRva(rva)
+	if off == 0 && rva != 0 {
+		return "", fmt.Errorf("address 0x%x does not resolve to any section", addr)
+	}
+	raw := pe.RawData()
+	if int64(off) >= int64(len(raw)) {
+		return "", fmt.Errorf("address 0x%x resolves outside file bounds", addr)
+	}
+	end := bytes.IndexByte(raw[off:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at address 0x%x", addr)
+	}
+	return string(raw[off : int(off)+end]), nil
+}
+
+// imageBase returns the optional header's ImageBase, for either PE32 or PE32+.
+func (pe *File) imageBase() uint64 {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.ImageBase
+	case ImageOptionalHeader32:
+		return uint64(oh.ImageBase)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseGoModInfo(t *testing.T) {
+	modInfo := "path\texample.com/cmd/tool\n" +
+		"mod\texample.com/cmd/tool\tv1.2.3\th1:abc=\n" +
+		"dep\tgolang.org/x/sys\tv0.10.0\th1:def=\n" +
+		"=>\texample.com/fork/sys\tv0.10.0-patched\th1:ghi=\n" +
+		"build\tGOARCH\tarm64\n" +
+		"build\tvcs.revision\tdeadbeef\n"
+
+	var info GoBuildInfo
+	parseGoModInfo(modInfo, &info)
+
+	if info.Path != "example.com/cmd/tool" {
+		t.Errorf("Path = %q, want example.com/cmd/tool", info.Path)
+	}
+	if info.Main.Path != "example.com/cmd/tool" || info.Main.Version != "v1.2.3" {
+		t.Errorf("Main = %+v", info.Main)
+	}
+	if len(info.Deps) != 1 || info.Deps[0].Path != "golang.org/x/sys" {
+		t.Fatalf("Deps = %+v", info.Deps)
+	}
+	if info.Deps[0].Replace == nil || info.Deps[0].Replace.Path != "example.com/fork/sys" {
+		t.Errorf("Deps[0].Replace = %+v", info.Deps[0].Replace)
+	}
+	wantSettings := map[string]string{"GOARCH": "arm64", "vcs.revision": "deadbeef"}
+	if len(info.Settings) != len(wantSettings) {
+		t.Fatalf("Settings = %+v", info.Settings)
+	}
+	for _, s := range info.Settings {
+		if wantSettings[s.Key] != s.Value {
+			t.Errorf("setting %s = %s, want %s", s.Key, s.Value, wantSettings[s.Key])
+		}
+	}
+}
+
+func TestReadGoBuildInfoString(t *testing.T) {
+	payload := []byte("go1.21.0")
+	buf := make([]byte, binary.MaxVarintLen64+len(payload)+4)
+	n := binary.PutUvarint(buf, uint64(len(payload)))
+	n += copy(buf[n:], payload)
+	tail := []byte{0xAA, 0xBB}
+	n += copy(buf[n:], tail)
+	buf = buf[:n]
+
+	s, rest, err := readGoBuildInfoString(buf)
+	if err != nil {
+		t.Fatalf("readGoBuildInfoString() error = %v", err)
+	}
+	if s != "go1.21.0" {
+		t.Errorf("s = %q, want go1.21.0", s)
+	}
+	if len(rest) != len(tail) || rest[0] != tail[0] {
+		t.Errorf("rest = %v, want %v", rest, tail)
+	}
+}
+
+func TestReadGoBuildInfoStringTruncated(t *testing.T) {
+	buf := []byte{10, 'a', 'b'} // declares 10 bytes, only 2 present
+	_, _, err := readGoBuildInfoString(buf)
+	if err != ErrGoBuildInfoTruncated {
+		t.Errorf("err = %v, want ErrGoBuildInfoTruncated", err)
+	}
+}
+
+func TestReadUintPtr(t *testing.T) {
+	b4 := []byte{0x01, 0x00, 0x00, 0x00}
+	if got := readUintPtr(binary.LittleEndian, b4); got != 1 {
+		t.Errorf("readUintPtr(4-byte LE) = %d, want 1", got)
+	}
+
+	b8 := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}
+	if got := readUintPtr(binary.BigEndian, b8); got != 2 {
+		t.Errorf("readUintPtr(8-byte BE) = %d, want 2", got)
+	}
+}
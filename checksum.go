@@ -0,0 +1,207 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Anomalies surfaced by checksum verification.
+const (
+	// AnoChecksumMismatch fires when the stored, non-zero CheckSum disagrees
+	// with the recomputed image checksum.
+	AnoChecksumMismatch = "Stored checksum does not match the computed image checksum"
+
+	// AnoChecksumZeroForDriver fires when the subsystem is NATIVE (a
+	// driver) but CheckSum is zero; drivers require a valid checksum for
+	// the OS loader to load them.
+	AnoChecksumZeroForDriver = "CheckSum is zero on a driver image, which requires a valid checksum to load"
+)
+
+// imageFileHeaderSize is sizeof(IMAGE_FILE_HEADER): Machine, NumberOfSections,
+// TimeDateStamp, PointerToSymbolTable, NumberOfSymbols, SizeOfOptionalHeader,
+// Characteristics.
+const imageFileHeaderSize = 20
+
+// checksumFieldOffset is the optional header's CheckSum field offset from
+// the start of the optional header, identical for PE32 and PE32+.
+const checksumFieldOffset = 64
+
+// ComputeChecksum implements the classic PE image checksum algorithm used
+// by IMAGHLP/the linker: the file is summed as a sequence of 16-bit
+// little-endian words (skipping the 4 bytes of OptionalHeader.CheckSum
+// itself, and zero-padding an odd trailing byte), folding carries into 16
+// bits as it goes, then the file's byte length is added.
+func (pe *File) ComputeChecksum() uint32 {
+	raw := pe.RawData()
+	// lfanew + "PE\0\0" + IMAGE_FILE_HEADER + CheckSum's offset within the
+	// optional header.
+	checksumOffset := int64(pe.DosHeader.AddressOfNewEXEHeader) + 4 + imageFileHeaderSize + checksumFieldOffset
+
+	var sum uint32
+	for i := 0; i+1 < len(raw); i += 2 {
+		if int64(i) == checksumOffset || int64(i) == checksumOffset+2 {
+			continue
+		}
+		word := uint32(binary.LittleEndian.Uint16(raw[i : i+2]))
+		sum += word
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	if len(raw)%2 == 1 {
+		word := uint32(raw[len(raw)-1])
+		sum += word
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	sum = (sum & 0xffff) + (sum >> 16)
+
+	return sum + uint32(len(raw))
+}
+
+// Checksum is an alias for ComputeChecksum, under the name IMAGEHLP's own
+// CheckSumMappedFile and most other PE tooling use for this value.
+func (pe *File) Checksum() uint32 {
+	return pe.ComputeChecksum()
+}
+
+// VerifyChecksum recomputes the image checksum and compares it against the
+// stored OptionalHeader.CheckSum.
+func (pe *File) VerifyChecksum() (stored, computed uint32, ok bool) {
+	stored = pe.checksumField()
+	computed = pe.ComputeChecksum()
+	return stored, computed, stored == computed
+}
+
+// checksumField returns OptionalHeader.CheckSum for either PE32 or PE32+.
+func (pe *File) checksumField() uint32 {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.CheckSum
+	case ImageOptionalHeader32:
+		return oh.CheckSum
+	default:
+		return 0
+	}
+}
+
+// checkChecksumAnomalies emits AnoChecksumMismatch when a non-zero stored
+// checksum disagrees with the computed one, and AnoChecksumZeroForDriver
+// when a driver ships with no checksum at all. VerifyChecksum walks every
+// byte of the file, so this is skipped unless the caller opts into it via
+// Options.VerifyChecksum, rather than paying that cost on every
+// GetAnomalies() call regardless of file size.
+func (pe *File) checkChecksumAnomalies() {
+	if pe.opts == nil || !pe.opts.VerifyChecksum {
+		return
+	}
+
+	stored, computed, ok := pe.VerifyChecksum()
+	if stored != 0 && !ok {
+		pe.addAnomalyStruct(Anomaly{
+			ID:       IDHeaderChecksumMismatch,
+			Category: CategoryHeader,
+			Severity: SeverityMedium,
+			Message:  AnoChecksumMismatch,
+		})
+		_ = computed
+	}
+
+	if pe.isNativeSubsystem() && stored == 0 {
+		pe.addAnomalyStruct(Anomaly{
+			ID:       IDHeaderChecksumZeroDriver,
+			Category: CategoryHeader,
+			Severity: SeverityHigh,
+			Message:  AnoChecksumZeroForDriver,
+		})
+	}
+}
+
+// isNativeSubsystem reports whether the image's subsystem is NATIVE, the
+// value used by kernel-mode drivers.
+func (pe *File) isNativeSubsystem() bool {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.Subsystem == ImageSubsystemNative
+	case ImageOptionalHeader32:
+		return oh.Subsystem == ImageSubsystemNative
+	default:
+		return false
+	}
+}
+
+// checksumReadChunk is the buffer size ComputeChecksumReader streams the
+// file through, so verifying a checksum never requires holding the whole
+// (potentially multi-hundred-MB) image in memory.
+const checksumReadChunk = 64 * 1024
+
+// ComputeChecksumReader computes the same image checksum as ComputeChecksum,
+// but streams size bytes from r instead of requiring the file already be
+// resident in memory, for callers checking large binaries one-off.
+func (pe *File) ComputeChecksumReader(r io.ReaderAt, size int64) (uint32, error) {
+	checksumOffset := int64(pe.DosHeader.AddressOfNewEXEHeader) + 4 + imageFileHeaderSize + checksumFieldOffset
+
+	var sum uint32
+	buf := make([]byte, checksumReadChunk)
+	var carry byte
+	haveCarry := false
+
+	for off := int64(0); off < size; off += int64(len(buf)) {
+		n := len(buf)
+		if remaining := size - off; int64(n) > remaining {
+			n = int(remaining)
+		}
+		if _, err := r.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		chunk := buf[:n]
+		i := 0
+		if haveCarry {
+			word := uint32(binary.LittleEndian.Uint16([]byte{carry, chunk[0]}))
+			if !inChecksumField(off-1, checksumOffset) {
+				sum += word
+				sum = (sum & 0xffff) + (sum >> 16)
+			}
+			i = 1
+			haveCarry = false
+		}
+		for ; i+1 < len(chunk); i += 2 {
+			if inChecksumField(off+int64(i), checksumOffset) {
+				continue
+			}
+			word := uint32(binary.LittleEndian.Uint16(chunk[i : i+2]))
+			sum += word
+			sum = (sum & 0xffff) + (sum >> 16)
+		}
+		if i < len(chunk) {
+			carry = chunk[i]
+			haveCarry = true
+		}
+	}
+	if haveCarry {
+		sum += uint32(carry)
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	sum = (sum & 0xffff) + (sum >> 16)
+
+	return sum + uint32(size), nil
+}
+
+func inChecksumField(offset, checksumOffset int64) bool {
+	return offset == checksumOffset || offset == checksumOffset+2
+}
+
+// UpdateChecksum recomputes the image checksum and writes it into
+// OptionalHeader.CheckSum's file offset through w, so a caller that has
+// modified the image can keep its checksum valid without re-parsing.
+func (pe *File) UpdateChecksum(w io.WriterAt) error {
+	checksum := pe.ComputeChecksum()
+	checksumOffset := int64(pe.DosHeader.AddressOfNewEXEHeader) + 4 + imageFileHeaderSize + checksumFieldOffset
+
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, checksum)
+	_, err := w.WriteAt(buf, checksumOffset)
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+type byteWriterAt struct {
+	buf []byte
+}
+
+func (w *byteWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if int(off)+len(p) > len(w.buf) {
+		return 0, bytes.ErrTooLarge
+	}
+	return copy(w.buf[off:], p), nil
+}
+
+func TestComputeChecksumReaderMatchesInMemory(t *testing.T) {
+	data := buildChecksummablePE(ImageSubsystemWindowsCUI)
+	// Pad to a size that isn't a clean multiple of the stream chunk size,
+	// and isn't even, to exercise the carry-byte path.
+	data = append(data, bytes.Repeat([]byte{0x7A}, checksumReadChunk+5)...)
+
+	f := &File{
+		Anomalies: []string{},
+		data:      data,
+		DosHeader: ImageDOSHeader{AddressOfNewEXEHeader: 0x80},
+	}
+
+	want := f.ComputeChecksum()
+	got, err := f.ComputeChecksumReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ComputeChecksumReader() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("ComputeChecksumReader() = %d, want %d (matching ComputeChecksum)", got, want)
+	}
+}
+
+func TestUpdateChecksumWritesComputedValue(t *testing.T) {
+	data := buildChecksummablePE(ImageSubsystemWindowsCUI)
+	f := &File{
+		Anomalies: []string{},
+		data:      data,
+		DosHeader: ImageDOSHeader{AddressOfNewEXEHeader: 0x80},
+	}
+
+	want := f.ComputeChecksum()
+
+	out := &byteWriterAt{buf: append([]byte{}, data...)}
+	if err := f.UpdateChecksum(out); err != nil {
+		t.Fatalf("UpdateChecksum() error = %v", err)
+	}
+
+	checksumOffset := int64(0x80) + 4 + imageFileHeaderSize + checksumFieldOffset
+	got := binary.LittleEndian.Uint32(out.buf[checksumOffset : checksumOffset+4])
+	if got != want {
+		t.Errorf("checksum written = %d, want %d", got, want)
+	}
+}
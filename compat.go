@@ -0,0 +1,174 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// HostArch identifies a CPU architecture a Windows image can be loaded and
+// run on, natively or through an emulation/translation layer.
+type HostArch int
+
+// Host architectures returned by File.CompatibleHosts.
+const (
+	HostUnknown HostArch = iota
+	HostI386
+	HostAMD64
+	HostARM
+	HostARM64
+)
+
+// String implements fmt.Stringer for HostArch.
+func (h HostArch) String() string {
+	switch h {
+	case HostI386:
+		return "i386"
+	case HostAMD64:
+		return "AMD64"
+	case HostARM:
+		return "ARM"
+	case HostARM64:
+		return "ARM64"
+	default:
+		return "unknown"
+	}
+}
+
+// EmulationMode classifies how a Windows loader executes an image on a
+// modern (ARM64-capable) host: natively, or through one of the WoW64,
+// ARM64EC, ARM64X, or xtajit translation layers.
+type EmulationMode int
+
+// Emulation modes returned by File.EmulationMode.
+const (
+	// ModeNative means the image runs directly as the host's native code,
+	// with no translation layer involved.
+	ModeNative EmulationMode = iota
+	// ModeWoW64 means a 32-bit (i386 or ARM) image running under the
+	// WoW64 subsystem of a 64-bit host.
+	ModeWoW64
+	// ModeARM64EC means an Arm64EC image: x64-compatible code that runs
+	// natively in an ARM64 process alongside native ARM64 code.
+	ModeARM64EC
+	// ModeARM64XAsX64 means the x64 half of an ARM64X dual-architecture
+	// image, as materialized by File.ARM64XAlternate.
+	ModeARM64XAsX64
+	// ModeARM64XAsARM64 means the native ARM64 half of an ARM64X
+	// dual-architecture image.
+	ModeARM64XAsARM64
+	// ModeXtajit means an AMD64 image translated by the xtajit x64
+	// emulator on an ARM64 host.
+	ModeXtajit
+)
+
+// String implements fmt.Stringer for EmulationMode.
+func (m EmulationMode) String() string {
+	switch m {
+	case ModeNative:
+		return "Native"
+	case ModeWoW64:
+		return "WoW64"
+	case ModeARM64EC:
+		return "ARM64EC"
+	case ModeARM64XAsX64:
+		return "ARM64XAsX64"
+	case ModeARM64XAsARM64:
+		return "ARM64XAsARM64"
+	case ModeXtajit:
+		return "xtajit"
+	default:
+		return "unknown"
+	}
+}
+
+// ARM64X code range entry types (Arm64EC/Arm64X code range table), used to
+// tell which half of a dual-architecture image a given RVA belongs to.
+const (
+	arm64XCodeRangeTypeARM64   = 0
+	arm64XCodeRangeTypeARM64EC = 1
+	arm64XCodeRangeTypeX64     = 2
+)
+
+// CompatibleHosts returns the set of host CPU architectures the Windows
+// loader can run this image on, natively or emulated, cross-referencing
+// the load config's CHPE metadata pointer so an Arm64EC image that
+// declares itself AMD64 in the file header is still recognized as
+// ARM64-loadable.
+func (pe *File) CompatibleHosts() []HostArch {
+	switch pe.machine() {
+	case ImageFileMachineARM64EC, ImageFileMachineARM64X:
+		return []HostArch{HostARM64, HostAMD64}
+	case ImageFileMachineARM64:
+		return []HostArch{HostARM64}
+	case ImageFileMachineAMD64:
+		if pe.chpeMetadataPointer() != 0 {
+			return []HostArch{HostARM64, HostAMD64}
+		}
+		return []HostArch{HostAMD64}
+	case ImageFileMachineI386:
+		return []HostArch{HostI386, HostAMD64, HostARM64}
+	case ImageFileMachineARM, ImageFileMachineARMNT:
+		return []HostArch{HostARM, HostARM64}
+	default:
+		return nil
+	}
+}
+
+// EmulationMode classifies how this image runs, judged from the image alone
+// (machine type, CHPE metadata, ARM64X entry point). A plain AMD64 image is
+// reported as ModeNative because the host architecture is not known here;
+// this method does not return ModeXtajit.
+func (pe *File) EmulationMode() EmulationMode {
+	switch pe.machine() {
+	case ImageFileMachineARM64EC:
+		return ModeARM64EC
+	case ImageFileMachineARM64X:
+		return pe.arm64xSubMode()
+	case ImageFileMachineARM64:
+		return ModeNative
+	case ImageFileMachineAMD64:
+		if pe.chpeMetadataPointer() != 0 {
+			return ModeARM64EC
+		}
+		// A plain AMD64 image is running on its native host architecture
+		// by far the common case. xtajit only enters the picture once the
+		// *host* is known to be ARM64, which this method has no way to
+		// observe from the image alone, so it must not be guessed here.
+		return ModeNative
+	case ImageFileMachineI386, ImageFileMachineARM, ImageFileMachineARMNT:
+		return ModeWoW64
+	default:
+		return ModeNative
+	}
+}
+
+// arm64xSubMode inspects the ARM64X code range table entry covering the
+// entry point to tell whether this view of the dual-architecture image is
+// its native ARM64 half or its x64 half; it defaults to the ARM64 half
+// when the code range table hasn't been parsed or the entry point isn't
+// covered by any range.
+func (pe *File) arm64xSubMode() EmulationMode {
+	rva := entryPointRVA(pe)
+	for _, cr := range pe.HybridPE.CodeRanges {
+		if rva < cr.StartRVA || rva >= cr.StartRVA+cr.Length {
+			continue
+		}
+		if cr.Type == arm64XCodeRangeTypeX64 {
+			return ModeARM64XAsX64
+		}
+		return ModeARM64XAsARM64
+	}
+	return ModeARM64XAsARM64
+}
+
+// entryPointRVA returns OptionalHeader.AddressOfEntryPoint for either PE32
+// or PE32+, or 0 if the optional header hasn't been parsed.
+func entryPointRVA(pe *File) uint32 {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.AddressOfEntryPoint
+	case ImageOptionalHeader32:
+		return oh.AddressOfEntryPoint
+	default:
+		return 0
+	}
+}
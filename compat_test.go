@@ -0,0 +1,109 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompatibleHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		machine ImageFileHeaderMachineType
+		want    []HostArch
+	}{
+		{"AMD64", ImageFileMachineAMD64, []HostArch{HostAMD64}},
+		{"I386", ImageFileMachineI386, []HostArch{HostI386, HostAMD64, HostARM64}},
+		{"ARM64", ImageFileMachineARM64, []HostArch{HostARM64}},
+		{"ARM64EC", ImageFileMachineARM64EC, []HostArch{HostARM64, HostAMD64}},
+		{"ARM64X", ImageFileMachineARM64X, []HostArch{HostARM64, HostAMD64}},
+		{"ARM", ImageFileMachineARM, []HostArch{HostARM, HostARM64}},
+		{"EBC", ImageFileMachineEBC, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: tt.machine}}}
+			got := f.CompatibleHosts()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompatibleHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompatibleHostsDetectsHybridARM64ECUnderAMD64(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineAMD64}},
+		LoadConfig: LoadConfigDirectory{
+			Struct64: ImageLoadConfigDirectory64{CHPEMetadataPointer: 0x4000},
+		},
+	}
+
+	want := []HostArch{HostARM64, HostAMD64}
+	if got := f.CompatibleHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CompatibleHosts() = %v, want %v", got, want)
+	}
+}
+
+func TestEmulationMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		machine ImageFileHeaderMachineType
+		want    EmulationMode
+	}{
+		{"AMD64", ImageFileMachineAMD64, ModeNative},
+		{"I386", ImageFileMachineI386, ModeWoW64},
+		{"ARM", ImageFileMachineARM, ModeWoW64},
+		{"ARM64", ImageFileMachineARM64, ModeNative},
+		{"ARM64EC", ImageFileMachineARM64EC, ModeARM64EC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: tt.machine}}}
+			if got := f.EmulationMode(); got != tt.want {
+				t.Errorf("EmulationMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmulationModeDetectsARM64ECHybridUnderAMD64(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineAMD64}},
+		LoadConfig: LoadConfigDirectory{
+			Struct64: ImageLoadConfigDirectory64{CHPEMetadataPointer: 0x4000},
+		},
+	}
+
+	if got := f.EmulationMode(); got != ModeARM64EC {
+		t.Errorf("EmulationMode() = %v, want %v", got, ModeARM64EC)
+	}
+}
+
+func TestEmulationModeARM64XSubMode(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			FileHeader:     ImageFileHeader{Machine: ImageFileMachineARM64X},
+			OptionalHeader: ImageOptionalHeader64{AddressOfEntryPoint: 0x1100},
+		},
+		HybridPE: HybridPE{
+			CodeRanges: []CodeRange{
+				{StartRVA: 0x1000, Length: 0x100, Type: arm64XCodeRangeTypeARM64},
+				{StartRVA: 0x2000, Length: 0x100, Type: arm64XCodeRangeTypeX64},
+			},
+		},
+	}
+	if got := f.EmulationMode(); got != ModeARM64XAsARM64 {
+		t.Errorf("EmulationMode() = %v, want %v", got, ModeARM64XAsARM64)
+	}
+
+	f.NtHeader.OptionalHeader = ImageOptionalHeader64{AddressOfEntryPoint: 0x2050}
+	if got := f.EmulationMode(); got != ModeARM64XAsX64 {
+		t.Errorf("EmulationMode() = %v, want %v", got, ModeARM64XAsX64)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// ImageDirectoryEntry identifies one of the optional header's data
+// directories, for use with GetDataDirectory and ParseDirectory.
+type ImageDirectoryEntry int
+
+// Standard data directory indices (IMAGE_DIRECTORY_ENTRY_*).
+const (
+	ImageDirectoryEntryExport ImageDirectoryEntry = iota
+	ImageDirectoryEntryImport
+	ImageDirectoryEntryResource
+	ImageDirectoryEntryException
+	ImageDirectoryEntrySecurity
+	ImageDirectoryEntryBaseReloc
+	ImageDirectoryEntryDebug
+	ImageDirectoryEntryArchitecture
+	ImageDirectoryEntryGlobalPtr
+	ImageDirectoryEntryTLS
+	ImageDirectoryEntryLoadConfig
+	ImageDirectoryEntryBoundImport
+	ImageDirectoryEntryIATEntry
+	ImageDirectoryEntryDelayImport
+	ImageDirectoryEntryCOMDescriptor
+	ImageDirectoryEntryReserved
+)
+
+// ImageNumberOfDirectoryEntries is the historical, and by far most common,
+// number of data directory entries in the optional header. Real-world PEs
+// may declare fewer (or, rarely, more) via NumberOfRvaAndSizes.
+const ImageNumberOfDirectoryEntries = 16
+
+// Fixed-size portions of the optional header, i.e. everything up to (but
+// not including) the DataDirectory array, used to validate that a declared
+// NumberOfRvaAndSizes actually fits within SizeOfOptionalHeader.
+const (
+	ImageOptionalHeader32FixedSize = 96
+	ImageOptionalHeader64FixedSize = 112
+)
+
+// Anomalies related to a variable-length data directory table. Real-world
+// signed Linux kernel images and some embedded PEs legitimately ship with
+// fewer than 16 data directories, so these are distinct from (and less
+// severe than) AnoNumberOfRvaAndSizes, which merely flags an uncommon count.
+const (
+	// AnoTruncatedDataDirectories fires when NumberOfRvaAndSizes * 8 plus the
+	// fixed optional header size exceeds SizeOfOptionalHeader, meaning the
+	// declared directory count doesn't fit in the header as sized.
+	AnoTruncatedDataDirectories = "Declared data directory count exceeds SizeOfOptionalHeader"
+
+	// AnoDataDirectoryCountMismatch fires when the parsed DataDirectory
+	// holds a populated (non-zero) entry at an index at or beyond
+	// NumberOfRvaAndSizes. A read through GetDataDirectory past the parsed
+	// entries returns ok=false and does not record it.
+	AnoDataDirectoryCountMismatch = "Data directory index referenced beyond NumberOfRvaAndSizes"
+
+	// AnoDataDirectoryRvaOutOfSection fires when a populated directory's
+	// VirtualAddress does not fall within any section, which usually means
+	// the directory is bogus or the image is truncated/corrupted.
+	AnoDataDirectoryRvaOutOfSection = "Data directory RVA does not fall within any section"
+)
+
+// numberOfRvaAndSizes returns the optional header's declared data directory
+// count, for either PE32 or PE32+.
+func (pe *File) numberOfRvaAndSizes() uint32 {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.NumberOfRvaAndSizes
+	case ImageOptionalHeader32:
+		return oh.NumberOfRvaAndSizes
+	default:
+		return 0
+	}
+}
+
+// dataDirectoryEntry returns the DataDirectory slice entry at index, for
+// either PE32 or PE32+. OptionalHeader.DataDirectory is parsed to hold
+// exactly NumberOfRvaAndSizes entries, not a fixed 16-entry array, so
+// signed Linux kernel images and other PEs that declare fewer (or,
+// rarely, more) are represented faithfully rather than padded or truncated
+// to the historical count here.
+func (pe *File) dataDirectoryEntry(index int) DataDirectory {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		if index < 0 || index >= len(oh.DataDirectory) {
+			return DataDirectory{}
+		}
+		return oh.DataDirectory[index]
+	case ImageOptionalHeader32:
+		if index < 0 || index >= len(oh.DataDirectory) {
+			return DataDirectory{}
+		}
+		return oh.DataDirectory[index]
+	default:
+		return DataDirectory{}
+	}
+}
+
+// dataDirectoryCount returns len(OptionalHeader.DataDirectory), i.e. the
+// number of entries actually parsed for this file.
+func (pe *File) dataDirectoryCount() int {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return len(oh.DataDirectory)
+	case ImageOptionalHeader32:
+		return len(oh.DataDirectory)
+	default:
+		return 0
+	}
+}
+
+// GetDataDirectory is a bounds-checked accessor for
+// OptionalHeader.DataDirectory[index]: it reports ok=false (rather than
+// panicking or silently returning a zero entry) when index falls outside
+// [0, len(DataDirectory)), so callers no longer need to assume a directory
+// count of exactly 16.
+func (pe *File) GetDataDirectory(index int) (dir DataDirectory, ok bool) {
+	if index < 0 || index >= pe.dataDirectoryCount() {
+		return DataDirectory{}, false
+	}
+	return pe.dataDirectoryEntry(index), true
+}
+
+// checkDataDirectoryAnomalies emits AnoTruncatedDataDirectories when the
+// declared directory count doesn't fit within SizeOfOptionalHeader, and
+// AnoDataDirectoryCountMismatch when a parsed DataDirectory entry at an
+// index at or beyond NumberOfRvaAndSizes is populated; indexes that other
+// parts of the parser read are not checked against the count. It is meant to be
+// called from GetAnomalies alongside the existing optional header checks.
+func (pe *File) checkDataDirectoryAnomalies() {
+	count := pe.numberOfRvaAndSizes()
+
+	var fixedSize uint32
+	var sizeOfOptionalHeader uint16
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		fixedSize = ImageOptionalHeader64FixedSize
+		sizeOfOptionalHeader = pe.NtHeader.FileHeader.SizeOfOptionalHeader
+		_ = oh
+	case ImageOptionalHeader32:
+		fixedSize = ImageOptionalHeader32FixedSize
+		sizeOfOptionalHeader = pe.NtHeader.FileHeader.SizeOfOptionalHeader
+		_ = oh
+	default:
+		return
+	}
+
+	if fixedSize+count*8 > uint32(sizeOfOptionalHeader) {
+		pe.addAnomaly(AnoTruncatedDataDirectories)
+	}
+
+	parsed := pe.dataDirectoryCount()
+	for i := 0; i < parsed; i++ {
+		dir := pe.dataDirectoryEntry(i)
+		if uint32(i) >= count && (dir.VirtualAddress != 0 || dir.Size != 0) {
+			pe.addAnomaly(AnoDataDirectoryCountMismatch)
+			break
+		}
+	}
+
+	for i := uint32(0); i < count && int(i) < parsed; i++ {
+		dir := pe.dataDirectoryEntry(int(i))
+		if dir.VirtualAddress == 0 {
+			continue
+		}
+		if !pe.rvaInAnySection(dir.VirtualAddress) {
+			pe.addAnomaly(AnoDataDirectoryRvaOutOfSection)
+			break
+		}
+	}
+}
+
+// rvaInAnySection reports whether rva falls within the virtual range of at
+// least one section.
+func (pe *File) rvaInAnySection(rva uint32) bool {
+	for _, sec := range pe.Sections {
+		start := sec.Header.VirtualAddress
+		end := start + sec.Header.VirtualSize
+		if rva >= start && rva < end {
+			return true
+		}
+	}
+	return false
+}
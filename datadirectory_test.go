@@ -0,0 +1,139 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func fileWithRvaAndSizes(count uint32, is64 bool) *File {
+	f := &File{
+		Anomalies: []string{},
+		Sections: []Section{
+			{Header: ImageSectionHeader{VirtualAddress: 0x1000, VirtualSize: 0x1000}},
+		},
+	}
+	dd := make([]DataDirectory, count)
+	if count > 0 {
+		dd[0] = DataDirectory{VirtualAddress: 0x1000, Size: 0x20}
+	}
+	if is64 {
+		f.NtHeader = ImageNtHeader{
+			FileHeader: ImageFileHeader{
+				SizeOfOptionalHeader: uint16(ImageOptionalHeader64FixedSize) + uint16(count)*8,
+			},
+			OptionalHeader: ImageOptionalHeader64{
+				NumberOfRvaAndSizes: count,
+				DataDirectory:       dd,
+			},
+		}
+	} else {
+		f.NtHeader = ImageNtHeader{
+			FileHeader: ImageFileHeader{
+				SizeOfOptionalHeader: uint16(ImageOptionalHeader32FixedSize) + uint16(count)*8,
+			},
+			OptionalHeader: ImageOptionalHeader32{
+				NumberOfRvaAndSizes: count,
+				DataDirectory:       dd,
+			},
+		}
+	}
+	return f
+}
+
+func TestGetDataDirectoryBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  uint32
+		index  int
+		wantOk bool
+	}{
+		{"first entry within 6-entry table", 6, 0, true},
+		{"last valid entry within 6-entry table", 6, 5, true},
+		{"just past 6-entry table", 6, 6, false},
+		{"within 11-entry table", 11, 10, true},
+		{"just past 11-entry table", 11, 11, false},
+		{"within default 16-entry table", 16, 15, true},
+		{"negative index", 16, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fileWithRvaAndSizes(tt.count, false)
+			_, ok := f.GetDataDirectory(tt.index)
+			if ok != tt.wantOk {
+				t.Errorf("GetDataDirectory(%d) with count=%d: ok = %v, want %v", tt.index, tt.count, ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCheckDataDirectoryAnomaliesCountMismatch(t *testing.T) {
+	// NumberOfRvaAndSizes says 6, but entry 0 (which we populated) is within
+	// bounds, so no mismatch should fire for a well-formed 6-entry table.
+	f := fileWithRvaAndSizes(6, false)
+	f.checkDataDirectoryAnomalies()
+	if len(f.Anomalies) != 0 {
+		t.Errorf("expected no anomalies for well-formed 6-entry table, got: %v", f.Anomalies)
+	}
+}
+
+func TestCheckDataDirectoryAnomaliesTruncated(t *testing.T) {
+	f := fileWithRvaAndSizes(16, false)
+	// Understate SizeOfOptionalHeader so it can't actually hold 16 entries.
+	f.NtHeader.FileHeader.SizeOfOptionalHeader = ImageOptionalHeader32FixedSize + 6*8
+
+	f.checkDataDirectoryAnomalies()
+
+	found := false
+	for _, a := range f.Anomalies {
+		if a == AnoTruncatedDataDirectories {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %s, got: %v", AnoTruncatedDataDirectories, f.Anomalies)
+	}
+}
+
+func TestCheckDataDirectoryAnomaliesPE64(t *testing.T) {
+	f := fileWithRvaAndSizes(11, true)
+	f.checkDataDirectoryAnomalies()
+	if len(f.Anomalies) != 0 {
+		t.Errorf("expected no anomalies for well-formed 11-entry PE32+ table, got: %v", f.Anomalies)
+	}
+}
+
+func TestCheckDataDirectoryAnomaliesRvaOutOfSection(t *testing.T) {
+	f := fileWithRvaAndSizes(6, false)
+	// Point the populated directory outside the only section's range.
+	oh := f.NtHeader.OptionalHeader.(ImageOptionalHeader32)
+	oh.DataDirectory[0].VirtualAddress = 0x9000
+	f.NtHeader.OptionalHeader = oh
+
+	f.checkDataDirectoryAnomalies()
+
+	found := false
+	for _, a := range f.Anomalies {
+		if a == AnoDataDirectoryRvaOutOfSection {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %s, got: %v", AnoDataDirectoryRvaOutOfSection, f.Anomalies)
+	}
+}
+
+func TestGetDataDirectoryEdgeCounts(t *testing.T) {
+	for _, count := range []uint32{6, 11, 16} {
+		t.Run("", func(t *testing.T) {
+			f := fileWithRvaAndSizes(count, false)
+			if _, ok := f.GetDataDirectory(int(count) - 1); !ok {
+				t.Errorf("last entry of a %d-entry table should be readable", count)
+			}
+			if _, ok := f.GetDataDirectory(int(count)); ok {
+				t.Errorf("entry %d of a %d-entry table should not be readable", count, count)
+			}
+		})
+	}
+}
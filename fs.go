@@ -0,0 +1,61 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// NewFS parses the PE file named name from fsys, mirroring io/fs's own
+// constructor shape. It lets callers wire the parser into scanning
+// pipelines that walk tar/zip archives, embedded VFS, or overlay
+// filesystems without going through the OS filesystem directly.
+//
+// When the opened fs.File also implements io.ReaderAt (true for *os.File,
+// and for testing/fstest's in-memory files) and reports its size via Stat,
+// NewFS reads the whole file through that interface into a buffer sized up
+// front, instead of letting io.ReadAll grow one; either way the entire file
+// is read into memory before parsing.
+//
+// The file system is taken only from the fsys argument: opts is not
+// consulted for one, and New itself opens paths via os.Open only.
+func NewFS(fsys fs.FS, name string, opts *Options) (*File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := readAllFromFS(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBytes(data, opts)
+}
+
+// readAllFromFS reads the entirety of f, preferring a size hint from Stat
+// plus io.ReaderAt when available to avoid io.ReadAll's repeated
+// reallocation as the buffer grows, and falling back to io.ReadAll when f
+// doesn't support random access or its size can't be determined up front.
+func readAllFromFS(f fs.File) ([]byte, error) {
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return io.ReadAll(f)
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return io.ReadAll(f)
+	}
+
+	data := make([]byte, info.Size())
+	if _, err := io.ReadFull(io.NewSectionReader(ra, 0, info.Size()), data); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadAllFromFSMapFS(t *testing.T) {
+	want := []byte("MZ" + string(make([]byte, 62)))
+	fsys := fstest.MapFS{
+		"sample.exe": &fstest.MapFile{Data: want},
+	}
+
+	f, err := fsys.Open("sample.exe")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	got, err := readAllFromFS(f)
+	if err != nil {
+		t.Fatalf("readAllFromFS() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("readAllFromFS() = %q, want %q", got, want)
+	}
+}
+
+func TestNewFSMissingFile(t *testing.T) {
+	fsys := fstest.MapFS{}
+
+	if _, err := NewFS(fsys, "missing.exe", &Options{}); err == nil {
+		t.Error("expected an error for a missing file, got nil")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// Fuzz harnesses for the parser's byte-driven entry points. Run with, e.g.:
+//
+//	go test -fuzz=FuzzParse -fuzztime=60s
+//	go test -fuzz=FuzzNewBytes -fuzztime=60s
+//	go test -fuzz=FuzzChecksum -fuzztime=60s
+//	go test -fuzz=FuzzArchitectureDirectory -fuzztime=60s
+//
+// Crashers found by `go test -fuzz` are written under testdata/fuzz/<name>/
+// and replayed automatically by `go test` from then on, turning every crash
+// into a permanent regression case.
+//
+// Resource- and security-directory-specific fuzzers are not included here:
+// this source tree doesn't contain the resource/security directory parsers
+// (they live in files this snapshot is missing), so there's nothing for
+// such a harness to drive yet. FuzzParse/FuzzNewBytes still exercise those
+// code paths indirectly once they're present, since Parse walks every
+// directory reachable from a given input.
+
+import "testing"
+
+func FuzzNewBytes(f *testing.F) {
+	f.Add(createMinimalPE())
+	f.Add([]byte("MZ"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := NewBytes(data, &Options{Fast: true})
+		if err != nil {
+			return
+		}
+		if file == nil {
+			t.Fatal("NewBytes returned a nil file with a nil error")
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(createMinimalPE())
+	f.Add([]byte("MZ"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := NewBytes(data, &Options{Fast: true})
+		if err != nil {
+			return
+		}
+
+		// Parse must never panic, and must never report more entries than
+		// the configured ceilings regardless of how a malicious input lies
+		// about its counts.
+		_ = file.Parse()
+
+		if len(file.Imports) > MaxDefaultImportEntriesCount {
+			t.Fatalf("Imports len = %d, exceeds MaxDefaultImportEntriesCount = %d",
+				len(file.Imports), MaxDefaultImportEntriesCount)
+		}
+		if len(file.Export.Functions) > MaxDefaultExportEntriesCount {
+			t.Fatalf("Export.Functions len = %d, exceeds MaxDefaultExportEntriesCount = %d",
+				len(file.Export.Functions), MaxDefaultExportEntriesCount)
+		}
+	})
+}
+
+func FuzzChecksum(f *testing.F) {
+	f.Add(createMinimalPE())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := NewBytes(data, &Options{Fast: true})
+		if err != nil {
+			return
+		}
+
+		// ComputeChecksum must never panic on arbitrary (possibly
+		// truncated, possibly malicious) input bytes.
+		_ = file.ComputeChecksum()
+	})
+}
+
+func FuzzArchitectureDirectory(f *testing.F) {
+	f.Add(uint32(0x1000), uint32(0x40), createMinimalPE())
+
+	f.Fuzz(func(t *testing.T, rva, size uint32, data []byte) {
+		file, err := NewBytes(data, &Options{Fast: true})
+		if err != nil {
+			return
+		}
+
+		// parseArchitectureDirectory must never panic regardless of what
+		// rva/size a malformed directory entry claims.
+		_ = file.parseArchitectureDirectory(rva, size)
+	})
+}
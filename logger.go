@@ -0,0 +1,78 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "log/slog"
+
+// Logger is the structured logging contract used by the parser to report
+// diagnostics as it walks a PE image. It mirrors the leveled,
+// key/value-pair shape common to go-kit, logr, and log/slog, so any of
+// those can be adapted to it with a thin wrapper.
+//
+// This is deliberately our own minimal interface rather than an adapter
+// over a specific framework's logger (e.g. kratos's log.Logger/log.Helper):
+// this package has no other third-party dependency, and pinning one here
+// purely for logging would make every caller's go.mod inherit it. Callers
+// already standardized on a leveled key/value logger elsewhere can satisfy
+// this interface with a few lines of glue, same as SlogLogger below does
+// for log/slog.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NopLogger is a Logger that discards everything. It is the default used
+// internally when Options.Logger is unset, so parsing code never needs to
+// nil-check the logger itself.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (NopLogger) Info(msg string, keyvals ...interface{})  {}
+func (NopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (NopLogger) Error(msg string, keyvals ...interface{}) {}
+
+// SlogLogger adapts a *slog.Logger to the Logger interface.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger backed by l, or the default slog logger
+// when l is nil.
+func NewSlogLogger(l *slog.Logger) SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return SlogLogger{Logger: l}
+}
+
+func (s SlogLogger) Debug(msg string, keyvals ...interface{}) { s.Logger.Debug(msg, keyvals...) }
+func (s SlogLogger) Info(msg string, keyvals ...interface{})  { s.Logger.Info(msg, keyvals...) }
+func (s SlogLogger) Warn(msg string, keyvals ...interface{})  { s.Logger.Warn(msg, keyvals...) }
+func (s SlogLogger) Error(msg string, keyvals ...interface{}) { s.Logger.Error(msg, keyvals...) }
+
+// log returns pe.logger, or NopLogger{} when unset, so call sites never
+// need to nil-check it themselves.
+func (pe *File) log() Logger {
+	if pe.logger == nil {
+		return NopLogger{}
+	}
+	return pe.logger
+}
+
+func (pe *File) logDebug(msg string, keyvals ...interface{}) { pe.log().Debug(msg, keyvals...) }
+func (pe *File) logInfo(msg string, keyvals ...interface{})  { pe.log().Info(msg, keyvals...) }
+func (pe *File) logWarn(msg string, keyvals ...interface{})  { pe.log().Warn(msg, keyvals...) }
+func (pe *File) logError(msg string, keyvals ...interface{}) { pe.log().Error(msg, keyvals...) }
+
+// addAnomalyLogged appends msg to File.Anomalies and logs it at Warn with
+// structured fields, so downstream tools can filter/route parser
+// diagnostics without string-matching File.Anomalies.
+func (pe *File) addAnomalyLogged(directory string, rva, size uint32, msg string) {
+	pe.addAnomaly(msg)
+	pe.logWarn(msg, "directory", directory, "rva", rva, "size", size,
+		"machine", pe.NtHeader.FileHeader.Machine.String())
+}
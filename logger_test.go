@@ -0,0 +1,57 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestNopLoggerDiscardsEverything(t *testing.T) {
+	var l Logger = NopLogger{}
+	// These must not panic; NopLogger has nothing else to assert on.
+	l.Debug("debug", "k", "v")
+	l.Info("info")
+	l.Warn("warn", "n", 1)
+	l.Error("error")
+}
+
+func TestFileLogDefaultsToNop(t *testing.T) {
+	file := &File{}
+	if _, ok := file.log().(NopLogger); !ok {
+		t.Errorf("log() = %T, want NopLogger when pe.logger is unset", file.log())
+	}
+}
+
+func TestFileLogUsesConfiguredLogger(t *testing.T) {
+	ml := &mockLogger{}
+	file := &File{logger: ml}
+
+	file.logWarn("something happened", "key", "value")
+
+	if ml.lastLevel != "warn" {
+		t.Errorf("lastLevel = %q, want warn", ml.lastLevel)
+	}
+	if ml.lastMessage != "something happened" {
+		t.Errorf("lastMessage = %q, want %q", ml.lastMessage, "something happened")
+	}
+}
+
+func TestAddAnomalyLogged(t *testing.T) {
+	ml := &mockLogger{}
+	file := &File{
+		Anomalies: []string{},
+		logger:    ml,
+		NtHeader: ImageNtHeader{
+			FileHeader: ImageFileHeader{Machine: ImageFileMachineARM64X},
+		},
+	}
+
+	file.addAnomalyLogged("Architecture", 0x1000, 0x10, "architecture directory anomaly")
+
+	if len(file.Anomalies) != 1 || file.Anomalies[0] != "architecture directory anomaly" {
+		t.Errorf("Anomalies = %v", file.Anomalies)
+	}
+	if ml.lastLevel != "warn" {
+		t.Errorf("lastLevel = %q, want warn", ml.lastLevel)
+	}
+}
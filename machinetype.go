@@ -0,0 +1,219 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// ArchFamily groups related ImageFileHeaderMachineType values under a
+// single instruction-set family, so callers can branch on architecture
+// without enumerating every specific machine type constant.
+type ArchFamily int
+
+// Architecture families returned by ImageFileHeaderMachineType.Family.
+// ArchMIPS, ArchPowerPC and ArchSH have String names but are not returned
+// by Family.
+const (
+	ArchUnknown ArchFamily = iota
+	ArchX86
+	ArchARM
+	ArchRISCV
+	ArchItanium
+	ArchMIPS
+	ArchPowerPC
+	ArchSH
+	ArchEBC
+)
+
+// String implements fmt.Stringer for ArchFamily.
+func (f ArchFamily) String() string {
+	switch f {
+	case ArchX86:
+		return "x86"
+	case ArchARM:
+		return "ARM"
+	case ArchRISCV:
+		return "RISC-V"
+	case ArchItanium:
+		return "Itanium"
+	case ArchMIPS:
+		return "MIPS"
+	case ArchPowerPC:
+		return "PowerPC"
+	case ArchSH:
+		return "SuperH"
+	case ArchEBC:
+		return "EFI Byte Code"
+	default:
+		return "unknown"
+	}
+}
+
+// Is64Bit reports whether m identifies a 64-bit architecture.
+func (m ImageFileHeaderMachineType) Is64Bit() bool {
+	switch m {
+	case ImageFileMachineAMD64, ImageFileMachineARM64, ImageFileMachineARM64EC,
+		ImageFileMachineARM64X, ImageFileMachineIA64,
+		ImageFileMachineRISCV64, ImageFileMachineRISCV128:
+		return true
+	default:
+		return false
+	}
+}
+
+// Is32Bit reports whether m identifies a 32-bit architecture.
+func (m ImageFileHeaderMachineType) Is32Bit() bool {
+	return m != ImageFileMachineUnknown && !m.Is64Bit()
+}
+
+// IsARM reports whether m is any ARM variant, 32- or 64-bit.
+func (m ImageFileHeaderMachineType) IsARM() bool {
+	switch m {
+	case ImageFileMachineARM, ImageFileMachineARMNT, ImageFileMachineARM64,
+		ImageFileMachineARM64EC, ImageFileMachineARM64X:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsX86Family reports whether m is an Intel/AMD x86 variant (i386 or
+// AMD64). It does not include IA64, which is Itanium, not x86.
+func (m ImageFileHeaderMachineType) IsX86Family() bool {
+	return m == ImageFileMachineI386 || m == ImageFileMachineAMD64
+}
+
+// IsRISCV reports whether m is any RISC-V width.
+func (m ImageFileHeaderMachineType) IsRISCV() bool {
+	switch m {
+	case ImageFileMachineRISCV32, ImageFileMachineRISCV64, ImageFileMachineRISCV128:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsEFIByteCode reports whether m is the EFI byte code machine type used
+// by EFI byte-code images (IMAGE_FILE_MACHINE_EBC).
+func (m ImageFileHeaderMachineType) IsEFIByteCode() bool {
+	return m == ImageFileMachineEBC
+}
+
+// IsLegacy reports whether m predates the architectures still actively
+// targeted by current toolchains, e.g. Itanium (IA64).
+func (m ImageFileHeaderMachineType) IsLegacy() bool {
+	return m == ImageFileMachineIA64
+}
+
+// Family classifies m into its instruction-set family. Machine types other
+// than x86, ARM, RISC-V, Itanium and EBC, including MIPS, PowerPC and
+// SuperH, are reported as ArchUnknown.
+func (m ImageFileHeaderMachineType) Family() ArchFamily {
+	switch {
+	case m.IsX86Family():
+		return ArchX86
+	case m.IsARM():
+		return ArchARM
+	case m.IsRISCV():
+		return ArchRISCV
+	case m == ImageFileMachineIA64:
+		return ArchItanium
+	case m == ImageFileMachineEBC:
+		return ArchEBC
+	default:
+		return ArchUnknown
+	}
+}
+
+// IsEFI reports whether s is any of the EFI subsystem values (application,
+// boot service driver, runtime driver, or ROM).
+func (s ImageOptionalHeaderSubsystemType) IsEFI() bool {
+	switch s {
+	case ImageSubsystemEFIApplication, ImageSubsystemEFIBootServiceDriver,
+		ImageSubsystemEFIRuntimeDriver, ImageSubsystemEFIRom:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsWindowsGUI reports whether s is the Windows GUI subsystem.
+func (s ImageOptionalHeaderSubsystemType) IsWindowsGUI() bool {
+	return s == ImageSubsystemWindowsGUI
+}
+
+// IsWindowsCUI reports whether s is the Windows console subsystem.
+func (s ImageOptionalHeaderSubsystemType) IsWindowsCUI() bool {
+	return s == ImageSubsystemWindowsCUI
+}
+
+// IsBootApplication reports whether s is the Windows boot application
+// subsystem used by bootmgr-style images.
+func (s ImageOptionalHeaderSubsystemType) IsBootApplication() bool {
+	return s == ImageSubsystemWindowsBootApplication
+}
+
+// IsXbox reports whether s is the Xbox subsystem.
+func (s ImageOptionalHeaderSubsystemType) IsXbox() bool {
+	return s == ImageSubsystemXBOX
+}
+
+// machine returns the file's machine type from the NT file header.
+func (pe *File) machine() ImageFileHeaderMachineType {
+	return pe.NtHeader.FileHeader.Machine
+}
+
+// subsystem returns the file's subsystem from the optional header, or
+// ImageSubsystemUnknown if the optional header hasn't been parsed.
+func (pe *File) subsystem() ImageOptionalHeaderSubsystemType {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.Subsystem
+	case ImageOptionalHeader32:
+		return oh.Subsystem
+	default:
+		return ImageSubsystemUnknown
+	}
+}
+
+// Is64Bit reports whether the image targets a 64-bit architecture.
+func (pe *File) Is64Bit() bool { return pe.machine().Is64Bit() }
+
+// Is32Bit reports whether the image targets a 32-bit architecture.
+func (pe *File) Is32Bit() bool { return pe.machine().Is32Bit() }
+
+// IsARM reports whether the image targets an ARM variant.
+func (pe *File) IsARM() bool { return pe.machine().IsARM() }
+
+// IsX86Family reports whether the image targets i386 or AMD64.
+func (pe *File) IsX86Family() bool { return pe.machine().IsX86Family() }
+
+// IsRISCV reports whether the image targets a RISC-V variant.
+func (pe *File) IsRISCV() bool { return pe.machine().IsRISCV() }
+
+// IsEFIByteCode reports whether the image targets the EFI byte code
+// machine type.
+func (pe *File) IsEFIByteCode() bool { return pe.machine().IsEFIByteCode() }
+
+// IsLegacy reports whether the image targets a legacy architecture such
+// as Itanium.
+func (pe *File) IsLegacy() bool { return pe.machine().IsLegacy() }
+
+// Family classifies the image's machine type into an instruction-set
+// family.
+func (pe *File) Family() ArchFamily { return pe.machine().Family() }
+
+// IsEFI reports whether the image's subsystem is any EFI variant.
+func (pe *File) IsEFI() bool { return pe.subsystem().IsEFI() }
+
+// IsWindowsGUI reports whether the image's subsystem is Windows GUI.
+func (pe *File) IsWindowsGUI() bool { return pe.subsystem().IsWindowsGUI() }
+
+// IsWindowsCUI reports whether the image's subsystem is Windows console.
+func (pe *File) IsWindowsCUI() bool { return pe.subsystem().IsWindowsCUI() }
+
+// IsBootApplication reports whether the image's subsystem is the Windows
+// boot application subsystem.
+func (pe *File) IsBootApplication() bool { return pe.subsystem().IsBootApplication() }
+
+// IsXbox reports whether the image's subsystem is Xbox.
+func (pe *File) IsXbox() bool { return pe.subsystem().IsXbox() }
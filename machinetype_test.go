@@ -0,0 +1,133 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestMachineTypePredicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		machine ImageFileHeaderMachineType
+		is64    bool
+		is32    bool
+		isARM   bool
+		isX86   bool
+		isRISCV bool
+		family  ArchFamily
+	}{
+		{"AMD64", ImageFileMachineAMD64, true, false, false, true, false, ArchX86},
+		{"I386", ImageFileMachineI386, false, true, false, true, false, ArchX86},
+		{"ARM", ImageFileMachineARM, false, true, true, false, false, ArchARM},
+		{"ARM64", ImageFileMachineARM64, true, false, true, false, false, ArchARM},
+		{"ARM64EC", ImageFileMachineARM64EC, true, false, true, false, false, ArchARM},
+		{"ARM64X", ImageFileMachineARM64X, true, false, true, false, false, ArchARM},
+		{"IA64", ImageFileMachineIA64, true, false, false, false, false, ArchItanium},
+		{"RISCV32", ImageFileMachineRISCV32, false, true, false, false, true, ArchRISCV},
+		{"RISCV64", ImageFileMachineRISCV64, true, false, false, false, true, ArchRISCV},
+		{"EBC", ImageFileMachineEBC, false, true, false, false, false, ArchEBC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.machine.Is64Bit(); got != tt.is64 {
+				t.Errorf("Is64Bit() = %v, want %v", got, tt.is64)
+			}
+			if got := tt.machine.Is32Bit(); got != tt.is32 {
+				t.Errorf("Is32Bit() = %v, want %v", got, tt.is32)
+			}
+			if got := tt.machine.IsARM(); got != tt.isARM {
+				t.Errorf("IsARM() = %v, want %v", got, tt.isARM)
+			}
+			if got := tt.machine.IsX86Family(); got != tt.isX86 {
+				t.Errorf("IsX86Family() = %v, want %v", got, tt.isX86)
+			}
+			if got := tt.machine.IsRISCV(); got != tt.isRISCV {
+				t.Errorf("IsRISCV() = %v, want %v", got, tt.isRISCV)
+			}
+			if got := tt.machine.Family(); got != tt.family {
+				t.Errorf("Family() = %v, want %v", got, tt.family)
+			}
+		})
+	}
+}
+
+func TestMachineTypeIsLegacyAndEFIByteCode(t *testing.T) {
+	if !ImageFileMachineIA64.IsLegacy() {
+		t.Error("IA64.IsLegacy() = false, want true")
+	}
+	if ImageFileMachineAMD64.IsLegacy() {
+		t.Error("AMD64.IsLegacy() = true, want false")
+	}
+	if !ImageFileMachineEBC.IsEFIByteCode() {
+		t.Error("EBC.IsEFIByteCode() = false, want true")
+	}
+	if ImageFileMachineAMD64.IsEFIByteCode() {
+		t.Error("AMD64.IsEFIByteCode() = true, want false")
+	}
+}
+
+func TestSubsystemPredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		subsystem ImageOptionalHeaderSubsystemType
+		isEFI     bool
+		isGUI     bool
+		isCUI     bool
+		isBootApp bool
+		isXbox    bool
+	}{
+		{"WindowsGUI", ImageSubsystemWindowsGUI, false, true, false, false, false},
+		{"WindowsCUI", ImageSubsystemWindowsCUI, false, false, true, false, false},
+		{"EFIApplication", ImageSubsystemEFIApplication, true, false, false, false, false},
+		{"EFIBootServiceDriver", ImageSubsystemEFIBootServiceDriver, true, false, false, false, false},
+		{"BootApplication", ImageSubsystemWindowsBootApplication, false, false, false, true, false},
+		{"Xbox", ImageSubsystemXBOX, false, false, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.subsystem.IsEFI(); got != tt.isEFI {
+				t.Errorf("IsEFI() = %v, want %v", got, tt.isEFI)
+			}
+			if got := tt.subsystem.IsWindowsGUI(); got != tt.isGUI {
+				t.Errorf("IsWindowsGUI() = %v, want %v", got, tt.isGUI)
+			}
+			if got := tt.subsystem.IsWindowsCUI(); got != tt.isCUI {
+				t.Errorf("IsWindowsCUI() = %v, want %v", got, tt.isCUI)
+			}
+			if got := tt.subsystem.IsBootApplication(); got != tt.isBootApp {
+				t.Errorf("IsBootApplication() = %v, want %v", got, tt.isBootApp)
+			}
+			if got := tt.subsystem.IsXbox(); got != tt.isXbox {
+				t.Errorf("IsXbox() = %v, want %v", got, tt.isXbox)
+			}
+		})
+	}
+}
+
+func TestFileMachineAndSubsystemWrappers(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			FileHeader:     ImageFileHeader{Machine: ImageFileMachineARM64},
+			OptionalHeader: ImageOptionalHeader64{Subsystem: ImageSubsystemWindowsGUI},
+		},
+	}
+
+	if !f.Is64Bit() {
+		t.Error("Is64Bit() = false, want true")
+	}
+	if !f.IsARM() {
+		t.Error("IsARM() = false, want true")
+	}
+	if f.Family() != ArchARM {
+		t.Errorf("Family() = %v, want %v", f.Family(), ArchARM)
+	}
+	if !f.IsWindowsGUI() {
+		t.Error("IsWindowsGUI() = false, want true")
+	}
+	if f.IsEFI() {
+		t.Error("IsEFI() = true, want false")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+// DllCharacteristics bits (IMAGE_DLLCHARACTERISTICS_*) in the optional
+// header's DllCharacteristics field.
+const (
+	ImageDllCharacteristicsHighEntropyVA        = 0x0020
+	ImageDllCharacteristicsDynamicBase          = 0x0040
+	ImageDllCharacteristicsForceIntegrity       = 0x0080
+	ImageDllCharacteristicsNXCompat             = 0x0100
+	ImageDllCharacteristicsNoIsolation          = 0x0200
+	ImageDllCharacteristicsNoSEH                = 0x0400
+	ImageDllCharacteristicsNoBind               = 0x0800
+	ImageDllCharacteristicsAppContainer         = 0x1000
+	ImageDllCharacteristicsWdmDriver            = 0x2000
+	ImageDllCharacteristicsGuardCF              = 0x4000
+	ImageDllCharacteristicsTerminalServiceAware = 0x8000
+)
+
+// GuardFlags bits (IMAGE_GUARD_*) in the load config directory's GuardFlags
+// field, describing Control Flow Guard and related mitigations.
+const (
+	ImageGuardCfInstrumented                 = 0x00000100
+	ImageGuardCfwInstrumented                = 0x00000200
+	ImageGuardCfFunctionTablePresent         = 0x00000400
+	ImageGuardSecurityCookieUnused           = 0x00000800
+	ImageGuardProtectDelayloadIat            = 0x00001000
+	ImageGuardDelayloadIatInItsOwnSection    = 0x00002000
+	ImageGuardCfExportSuppressionInfoPresent = 0x00004000
+	ImageGuardCfEnableExportSuppression      = 0x00008000
+	ImageGuardCfLongjumpTablePresent         = 0x00010000
+	ImageGuardRfInstrumented                 = 0x00020000
+	ImageGuardRfEnable                       = 0x00040000
+	ImageGuardRfStrict                       = 0x00080000
+	ImageGuardRetpolinePresent               = 0x00100000
+	ImageGuardEhContinuationTablePresent     = 0x00400000
+	ImageGuardXfgEnabled                     = 0x00800000
+)
+
+// DllCharacteristicsEx bits (IMAGE_DLLCHARACTERISTICS_EX_*) in the load
+// config directory's DllCharacteristicsEx field, describing CET (shadow
+// stack) compatibility.
+const (
+	ImageDllCharacteristicsExCetCompat                      = 0x01
+	ImageDllCharacteristicsExCetCompatStrictMode            = 0x02
+	ImageDllCharacteristicsExCetSetContextIPValidationRelax = 0x04
+	ImageDllCharacteristicsExCetDynamicApisAllowInProc      = 0x08
+	ImageDllCharacteristicsExForwardCfiCompat               = 0x40
+	ImageDllCharacteristicsExHotpatchCompatible             = 0x80
+)
+
+// DependentLoadFlags bits relevant to DLL search-path hardening
+// (LOAD_LIBRARY_SEARCH_* defaults applied at load time).
+const imageDependentLoadFlagsSafeDefaults = 0x1000 // LOAD_LIBRARY_SEARCH_DEFAULT_DIRS
+
+// MitigationReport is a structured summary of the exploit mitigations an
+// image opts into, fusing the optional header's DllCharacteristics with
+// the adjacent hardening fields of the load config directory.
+type MitigationReport struct {
+	HasASLR            bool
+	HasHighEntropyASLR bool
+	HasDEP             bool
+	HasCFG             bool
+	HasXFG             bool
+	HasCET             bool
+	HasCETStrictMode   bool
+	HasSEH             bool
+	HasForceIntegrity  bool
+	HasRetpoline       bool
+	HasSafeDllSearch   bool
+	IsAppContainer     bool
+	// Score is a simple count of the mitigations above that are enabled,
+	// weighted so CFG/XFG/CET (the mitigations hardest for an exploit to
+	// route around) count double; IsAppContainer and HasCETStrictMode do not
+	// count. It is meant as a rough ranking signal for triage, not a
+	// security guarantee.
+	Score int
+	// Rationale explains, in order, which field drove each true flag above,
+	// except HasSEH: for it an entry is added only when NoSEH clears the
+	// flag. No entry explains Score.
+	Rationale []string
+}
+
+// SecurityMitigations inspects the optional header's DllCharacteristics
+// and the load config directory to report which exploit mitigations this
+// image opts into.
+func (pe *File) SecurityMitigations() MitigationReport {
+	var report MitigationReport
+	dll := pe.dllCharacteristics()
+
+	report.HasASLR = dll&uint16(ImageDllCharacteristicsDynamicBase) != 0
+	report.HasHighEntropyASLR = dll&uint16(ImageDllCharacteristicsHighEntropyVA) != 0
+	report.HasDEP = dll&uint16(ImageDllCharacteristicsNXCompat) != 0
+	report.HasForceIntegrity = dll&uint16(ImageDllCharacteristicsForceIntegrity) != 0
+	report.IsAppContainer = dll&uint16(ImageDllCharacteristicsAppContainer) != 0
+	report.HasSEH = dll&uint16(ImageDllCharacteristicsNoSEH) == 0
+	report.HasCFG = dll&uint16(ImageDllCharacteristicsGuardCF) != 0
+
+	if report.HasASLR {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.DynamicBase is set: image supports ASLR")
+	}
+	if report.HasHighEntropyASLR {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.HighEntropyVA is set: image supports high-entropy ASLR")
+	}
+	if report.HasDEP {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.NXCompat is set: image supports DEP/NX")
+	}
+	if report.HasForceIntegrity {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.ForceIntegrity is set: the loader verifies the image's digital signature")
+	}
+	if report.IsAppContainer {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.AppContainer is set: image must run in an AppContainer")
+	}
+	if !report.HasSEH {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.NoSEH is set: image has no valid exception handlers registered")
+	}
+	if report.HasCFG {
+		report.Rationale = append(report.Rationale, "DllCharacteristics.GuardCF is set: image is instrumented for Control Flow Guard")
+	}
+
+	guardFlags, dllCharEx, dependentLoadFlags := pe.loadConfigMitigationFields()
+
+	if report.HasCFG && guardFlags&ImageGuardXfgEnabled != 0 {
+		report.HasXFG = true
+		report.Rationale = append(report.Rationale, "LoadConfig.GuardFlags has IMAGE_GUARD_XFG_ENABLED: image uses type-hashed Control Flow Guard (XFG)")
+	}
+	if guardFlags&ImageGuardRetpolinePresent != 0 {
+		report.HasRetpoline = true
+		report.Rationale = append(report.Rationale, "LoadConfig.GuardFlags has IMAGE_GUARD_RETPOLINE_PRESENT: image was compiled with retpoline mitigations")
+	}
+	if dllCharEx&ImageDllCharacteristicsExCetCompat != 0 {
+		report.HasCET = true
+		report.Rationale = append(report.Rationale, "LoadConfig.DllCharacteristicsEx has CET_COMPAT: image is compatible with shadow-stack enforcement")
+	}
+	if dllCharEx&ImageDllCharacteristicsExCetCompatStrictMode != 0 {
+		report.HasCETStrictMode = true
+		report.Rationale = append(report.Rationale, "LoadConfig.DllCharacteristicsEx has CET_COMPAT_STRICT_MODE: CET is required, not just tolerated")
+	}
+	if dependentLoadFlags&imageDependentLoadFlagsSafeDefaults != 0 {
+		report.HasSafeDllSearch = true
+		report.Rationale = append(report.Rationale, "LoadConfig.DependentLoadFlags restricts dependent-DLL search to safe default directories")
+	}
+
+	report.Score = scoreMitigations(report)
+	return report
+}
+
+// scoreMitigations weighs CFG, XFG, and CET double since they're the
+// hardest mitigations for an exploit to route around; every other true
+// flag in report counts once, except IsAppContainer and HasCETStrictMode,
+// which do not count.
+func scoreMitigations(report MitigationReport) int {
+	score := 0
+	if report.HasASLR {
+		score++
+	}
+	if report.HasHighEntropyASLR {
+		score++
+	}
+	if report.HasDEP {
+		score++
+	}
+	if report.HasForceIntegrity {
+		score++
+	}
+	if report.HasSEH {
+		score++
+	}
+	if report.HasRetpoline {
+		score++
+	}
+	if report.HasSafeDllSearch {
+		score++
+	}
+	if report.HasCFG {
+		score += 2
+	}
+	if report.HasXFG {
+		score += 2
+	}
+	if report.HasCET {
+		score += 2
+	}
+	return score
+}
+
+// dllCharacteristics returns OptionalHeader.DllCharacteristics for either
+// PE32 or PE32+, or 0 if the optional header hasn't been parsed.
+func (pe *File) dllCharacteristics() uint16 {
+	switch oh := pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return uint16(oh.DllCharacteristics)
+	case ImageOptionalHeader32:
+		return uint16(oh.DllCharacteristics)
+	default:
+		return 0
+	}
+}
+
+// loadConfigMitigationFields returns the GuardFlags, DllCharacteristicsEx,
+// and DependentLoadFlags fields from whichever load config directory
+// variant was parsed.
+func (pe *File) loadConfigMitigationFields() (guardFlags uint32, dllCharEx uint32, dependentLoadFlags uint16) {
+	if pe.LoadConfig.Struct64.Size != 0 {
+		s := pe.LoadConfig.Struct64
+		return s.GuardFlags, s.DllCharacteristicsEx, s.DependentLoadFlags
+	}
+	s := pe.LoadConfig.Struct32
+	return s.GuardFlags, s.DllCharacteristicsEx, s.DependentLoadFlags
+}
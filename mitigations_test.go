@@ -0,0 +1,98 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import "testing"
+
+func TestSecurityMitigationsModernHardenedBinary(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			OptionalHeader: ImageOptionalHeader64{
+				DllCharacteristics: ImageDllCharacteristicsDynamicBase |
+					ImageDllCharacteristicsHighEntropyVA |
+					ImageDllCharacteristicsNXCompat |
+					ImageDllCharacteristicsGuardCF,
+			},
+		},
+		LoadConfig: LoadConfigDirectory{
+			Struct64: ImageLoadConfigDirectory64{
+				Size:                 1,
+				GuardFlags:           ImageGuardXfgEnabled | ImageGuardRetpolinePresent,
+				DllCharacteristicsEx: ImageDllCharacteristicsExCetCompat,
+				DependentLoadFlags:   imageDependentLoadFlagsSafeDefaults,
+			},
+		},
+	}
+
+	report := f.SecurityMitigations()
+
+	if !report.HasASLR || !report.HasHighEntropyASLR || !report.HasDEP || !report.HasCFG {
+		t.Fatalf("expected ASLR/high-entropy ASLR/DEP/CFG to be set, got %+v", report)
+	}
+	if !report.HasXFG {
+		t.Error("expected HasXFG = true")
+	}
+	if !report.HasRetpoline {
+		t.Error("expected HasRetpoline = true")
+	}
+	if !report.HasCET {
+		t.Error("expected HasCET = true")
+	}
+	if !report.HasSafeDllSearch {
+		t.Error("expected HasSafeDllSearch = true")
+	}
+	if !report.HasSEH {
+		t.Error("expected HasSEH = true (NoSEH not set)")
+	}
+	if report.IsAppContainer || report.HasForceIntegrity {
+		t.Errorf("did not expect AppContainer or ForceIntegrity, got %+v", report)
+	}
+	if len(report.Rationale) == 0 {
+		t.Error("expected non-empty Rationale")
+	}
+	if report.Score <= 0 {
+		t.Errorf("expected a positive Score, got %d", report.Score)
+	}
+}
+
+func TestSecurityMitigationsUnhardenedBinary(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			OptionalHeader: ImageOptionalHeader32{
+				DllCharacteristics: ImageDllCharacteristicsNoSEH,
+			},
+		},
+	}
+
+	report := f.SecurityMitigations()
+
+	if report.HasASLR || report.HasDEP || report.HasCFG || report.HasXFG || report.HasCET {
+		t.Errorf("expected no mitigations set, got %+v", report)
+	}
+	if report.HasSEH {
+		t.Error("expected HasSEH = false since NoSEH is set")
+	}
+	if report.Score != 0 {
+		t.Errorf("Score = %d, want 0", report.Score)
+	}
+}
+
+func TestSecurityMitigationsAppContainerAndForceIntegrity(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			OptionalHeader: ImageOptionalHeader64{
+				DllCharacteristics: ImageDllCharacteristicsAppContainer | ImageDllCharacteristicsForceIntegrity,
+			},
+		},
+	}
+
+	report := f.SecurityMitigations()
+	if !report.IsAppContainer {
+		t.Error("expected IsAppContainer = true")
+	}
+	if !report.HasForceIntegrity {
+		t.Error("expected HasForceIntegrity = true")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// Anomalies surfaced by overlay detection.
+const (
+	// AnoOverlayPresent fires whenever data is appended past the last
+	// section, a common installer/dropper technique.
+	AnoOverlayPresent = "Data appended past the last section (overlay present)"
+
+	// AnoOverlayHighEntropy fires when the overlay's Shannon entropy
+	// exceeds 7.5, consistent with compressed or encrypted payloads.
+	AnoOverlayHighEntropy = "Overlay data has high entropy, consistent with compression or encryption"
+)
+
+// overlayHighEntropyThreshold mirrors the threshold already used for
+// section-level packing heuristics.
+const overlayHighEntropyThreshold = 7.5
+
+// OverlayOffset returns the file offset at which data appended past the end
+// of the last section (the overlay) begins. It ignores the certificate
+// table's own range, since an authenticode signature is expected to live
+// past the sections. When there is no overlay, it returns the file size.
+func (pe *File) OverlayOffset() int64 {
+	raw := pe.RawData()
+	fileSize := int64(len(raw))
+
+	end := pe.lastSectionEnd()
+
+	if certDir, ok := pe.GetDataDirectory(int(ImageDirectoryEntrySecurity)); ok && certDir.Size > 0 {
+		certEnd := int64(certDir.VirtualAddress) + int64(certDir.Size)
+		if certEnd > end {
+			end = certEnd
+		}
+	}
+
+	if end > fileSize {
+		end = fileSize
+	}
+	return end
+}
+
+// HasOverlay reports whether the file has any data appended past the end of
+// its last section.
+func (pe *File) HasOverlay() bool {
+	return pe.OverlayOffset() < int64(len(pe.RawData()))
+}
+
+// NewOverlayReader returns a reader over the overlay's bytes, bounded by
+// Options.OverlayMaxScan when the caller configured one. It returns an empty
+// reader when there is no overlay.
+func (pe *File) NewOverlayReader() (io.Reader, error) {
+	raw := pe.RawData()
+	fileSize := int64(len(raw))
+	offset := pe.OverlayOffset()
+
+	if offset >= fileSize {
+		return bytes.NewReader(nil), nil
+	}
+
+	end := fileSize
+	if pe.opts != nil && pe.opts.OverlayMaxScan > 0 && int64(pe.opts.OverlayMaxScan) < fileSize-offset {
+		end = offset + int64(pe.opts.OverlayMaxScan)
+	}
+
+	return bytes.NewReader(raw[offset:end]), nil
+}
+
+// Overlay returns the bytes appended past the end of the last section, i.e.
+// the data a NewOverlayReader would stream. It returns a nil slice when
+// there is no overlay.
+func (pe *File) Overlay() ([]byte, error) {
+	r, err := pe.NewOverlayReader()
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// lastSectionEnd returns the highest PointerToRawData+SizeOfRawData across
+// every section, i.e. where section data ends on disk.
+func (pe *File) lastSectionEnd() int64 {
+	var end int64
+	for _, sec := range pe.Sections {
+		secEnd := int64(sec.Header.PointerToRawData) + int64(sec.Header.SizeOfRawData)
+		if secEnd > end {
+			end = secEnd
+		}
+	}
+	return end
+}
+
+// checkOverlayAnomalies emits AnoOverlayPresent when trailing data exists
+// past the last section, and additionally AnoOverlayHighEntropy when that
+// data's Shannon entropy suggests it is compressed or encrypted.
+func (pe *File) checkOverlayAnomalies() {
+	if !pe.HasOverlay() {
+		return
+	}
+	pe.addAnomaly(AnoOverlayPresent)
+
+	overlay, err := pe.Overlay()
+	if err != nil || len(overlay) == 0 {
+		return
+	}
+
+	entropy := overlayShannonEntropy(overlay)
+	if entropy > overlayHighEntropyThreshold {
+		pe.addAnomalyStruct(Anomaly{
+			ID:       IDEntropyElevatedProportion,
+			Category: CategoryPacking,
+			Severity: SeverityMedium,
+			Message:  AnoOverlayHighEntropy,
+			Location: AnomalyLocation{SectionIndex: -1, DirectoryEntry: -1, FileOffset: pe.OverlayOffset()},
+		})
+	}
+}
+
+// overlayShannonEntropy computes the Shannon entropy, in bits per byte, of data.
+func overlayShannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var freq [256]int
+	for _, b := range data {
+		freq[b]++
+	}
+	var entropy float64
+	n := float64(len(data))
+	for _, c := range freq {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fileWithOverlay(sectionData, overlay []byte) *File {
+	data := append(append([]byte{}, sectionData...), overlay...)
+	return &File{
+		Anomalies: []string{},
+		data:      data,
+		Sections: []Section{
+			{Header: ImageSectionHeader{PointerToRawData: 0, SizeOfRawData: uint32(len(sectionData))}},
+		},
+	}
+}
+
+func TestOverlayNoneWhenFileEndsWithLastSection(t *testing.T) {
+	f := fileWithOverlay(bytes.Repeat([]byte{0x00}, 64), nil)
+
+	if f.HasOverlay() {
+		t.Errorf("HasOverlay() = true, want false")
+	}
+	overlay, err := f.Overlay()
+	if err != nil {
+		t.Fatalf("Overlay() error = %v", err)
+	}
+	if len(overlay) != 0 {
+		t.Errorf("len(overlay) = %d, want 0", len(overlay))
+	}
+}
+
+func TestOverlayPresent(t *testing.T) {
+	overlay := bytes.Repeat([]byte{0xAA}, 32)
+	f := fileWithOverlay(bytes.Repeat([]byte{0x00}, 64), overlay)
+
+	if !f.HasOverlay() {
+		t.Fatalf("HasOverlay() = false, want true")
+	}
+	if offset := f.OverlayOffset(); offset != 64 {
+		t.Errorf("OverlayOffset() = %d, want 64", offset)
+	}
+	got, err := f.Overlay()
+	if err != nil {
+		t.Fatalf("Overlay() error = %v", err)
+	}
+	if !bytes.Equal(got, overlay) {
+		t.Errorf("Overlay() = %v, want %v", got, overlay)
+	}
+
+	f.checkOverlayAnomalies()
+	found := false
+	for _, a := range f.Anomalies {
+		if a == AnoOverlayPresent {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %s, got: %v", AnoOverlayPresent, f.Anomalies)
+	}
+}
+
+func TestOverlayHighEntropy(t *testing.T) {
+	// A pseudo-random byte sequence is a reasonable high-entropy stand-in
+	// without pulling in crypto/rand in a test.
+	overlay := make([]byte, 512)
+	x := uint32(0x9E3779B9)
+	for i := range overlay {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		overlay[i] = byte(x)
+	}
+	f := fileWithOverlay(bytes.Repeat([]byte{0x00}, 64), overlay)
+
+	f.checkOverlayAnomalies()
+	found := false
+	for _, a := range f.Anomalies {
+		if a == AnoOverlayHighEntropy {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %s, got: %v", AnoOverlayHighEntropy, f.Anomalies)
+	}
+}
+
+func TestOverlayMaxScanCap(t *testing.T) {
+	overlay := bytes.Repeat([]byte{0xAA}, 1000)
+	f := fileWithOverlay(bytes.Repeat([]byte{0x00}, 64), overlay)
+	f.opts = &Options{OverlayMaxScan: 100}
+
+	got, err := f.Overlay()
+	if err != nil {
+		t.Fatalf("Overlay() error = %v", err)
+	}
+	if len(got) != 100 {
+		t.Errorf("len(Overlay()) = %d, want 100 (capped by OverlayMaxScan)", len(got))
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"fmt"
+
+	"github.com/saferwall/pe/packerdb"
+)
+
+// genericPackingEntropyThreshold and genericPackingProportion mirror the
+// thresholds the generic, signature-less packing heuristic already uses
+// elsewhere in GetAnomalies: a section counts as "elevated entropy" above
+// the threshold, and the binary looks packed once that proportion of its
+// sections qualify.
+const (
+	genericPackingEntropyThreshold = 7.5
+	genericPackingProportion       = 0.5
+)
+
+// packerTarget adapts *File to the packerdb.Target interface signatures are
+// evaluated against. packerdb cannot import this package directly (this
+// package already imports packerdb to run DetectPacker, and Go doesn't
+// allow the cycle that importing back would create), so the adapter lives
+// here instead.
+type packerTarget struct{ pe *File }
+
+func (t packerTarget) Sections() []packerdb.SectionView {
+	out := make([]packerdb.SectionView, len(t.pe.Sections))
+	for i, s := range t.pe.Sections {
+		out[i] = packerdb.SectionView{Name: s.NameString()}
+	}
+	return out
+}
+
+func (t packerTarget) EntryPointRVA() uint32 {
+	switch oh := t.pe.NtHeader.OptionalHeader.(type) {
+	case ImageOptionalHeader64:
+		return oh.AddressOfEntryPoint
+	case ImageOptionalHeader32:
+		return oh.AddressOfEntryPoint
+	default:
+		return 0
+	}
+}
+
+func (t packerTarget) ReadData(rva uint32, size int) ([]byte, error) {
+	return t.pe.GetData(rva, size)
+}
+
+// PackerMatch is a concrete packer-family identification produced by
+// DetectPacker, together with the evidence that triggered it.
+type PackerMatch struct {
+	// Name is the packer family, e.g. "UPX" or "ASPack".
+	Name string `json:"name"`
+	// Version is the packer version where derivable from its evidence, or
+	// empty when it can't be determined.
+	Version string `json:"version,omitempty"`
+	// Confidence is how sure this match is, 0..1.
+	Confidence float64 `json:"confidence"`
+	// Evidence describes what triggered the match, e.g. a section name, an
+	// entry-point byte pattern, or an import-table shape.
+	Evidence string `json:"evidence"`
+}
+
+// DetectPacker runs the packerdb signature set against the file's section
+// names and entry point (the import table is not examined), and returns every concrete packer-family
+// identification found. Each positive match is also fed back into the
+// anomaly list via addAnomalyStruct under CategoryPacking, so a specific
+// family name (e.g. "Packer identified: UPX v3.96") takes the place of the
+// generic "Suspicious packed section name" heuristic wherever a signature
+// actually fires. When no signature matches but the section entropy profile
+// still looks packed, the generic entropy-proportion heuristic is used as a
+// fallback so packed-but-unidentified binaries are still flagged.
+func (pe *File) DetectPacker() ([]PackerMatch, error) {
+	var matches []PackerMatch
+	for _, m := range packerdb.Run(packerTarget{pe: pe}) {
+		matches = append(matches, PackerMatch{
+			Name:       m.Name,
+			Version:    m.Version,
+			Confidence: m.Confidence,
+			Evidence:   m.Evidence,
+		})
+	}
+
+	if len(matches) == 0 {
+		if pe.looksGenericallyPacked() {
+			pe.addAnomalyStruct(Anomaly{
+				ID:       IDEntropyElevatedProportion,
+				Category: CategoryPacking,
+				Severity: SeverityMedium,
+				Message:  "High proportion of sections with elevated entropy",
+			})
+		}
+		return matches, nil
+	}
+
+	for _, m := range matches {
+		msg := "Packer identified: " + m.Name
+		if m.Version != "" {
+			msg += " v" + m.Version
+		}
+		pe.addAnomalyStruct(Anomaly{
+			ID:       IDPackSuspiciousName,
+			Category: CategoryPacking,
+			Severity: SeverityHigh,
+			Message:  fmt.Sprintf("%s (%s, confidence %.2f)", msg, m.Evidence, m.Confidence),
+		})
+	}
+	return matches, nil
+}
+
+// looksGenericallyPacked reports whether at least half of the file's
+// sections have entropy above genericPackingEntropyThreshold, the same
+// signature-less heuristic the pre-existing detectPackedBinary check uses.
+func (pe *File) looksGenericallyPacked() bool {
+	if len(pe.Sections) == 0 {
+		return false
+	}
+	var elevated int
+	for _, sec := range pe.Sections {
+		if sec.Entropy != nil && *sec.Entropy > genericPackingEntropyThreshold {
+			elevated++
+		}
+	}
+	return float64(elevated)/float64(len(pe.Sections)) >= genericPackingProportion
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"strings"
+	"testing"
+)
+
+func sectionNamed(name string) Section {
+	var hdr ImageSectionHeader
+	copy(hdr.Name[:], name)
+	return Section{Header: hdr}
+}
+
+func TestDetectPackerSectionNameSignatures(t *testing.T) {
+	tests := []struct {
+		name       string
+		sections   []Section
+		wantPacker string
+	}{
+		{"UPX", []Section{sectionNamed("UPX0"), sectionNamed("UPX1")}, "UPX"},
+		{"ASPack", []Section{sectionNamed(".aspack"), sectionNamed(".adata")}, "ASPack"},
+		{"MPRESS", []Section{sectionNamed("MPRESS1"), sectionNamed("MPRESS2")}, "MPRESS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{Anomalies: []string{}, Sections: tt.sections}
+
+			matches, err := f.DetectPacker()
+			if err != nil {
+				t.Fatalf("DetectPacker() error = %v", err)
+			}
+
+			found := false
+			for _, m := range matches {
+				if m.Name == tt.wantPacker {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected a %s match, got: %+v", tt.wantPacker, matches)
+			}
+
+			foundAnomaly := false
+			for _, a := range f.Anomalies {
+				if strings.Contains(a, "Packer identified: "+tt.wantPacker) {
+					foundAnomaly = true
+				}
+			}
+			if !foundAnomaly {
+				t.Errorf("expected a packer-identified anomaly, got: %v", f.Anomalies)
+			}
+
+			foundStructured := false
+			for _, a := range f.AnomalyDetails {
+				if a.ID == IDPackSuspiciousName && a.Category == CategoryPacking {
+					foundStructured = true
+				}
+			}
+			if !foundStructured {
+				t.Errorf("expected a structured CategoryPacking anomaly via addAnomalyStruct, got: %+v", f.AnomalyDetails)
+			}
+		})
+	}
+}
+
+func TestDetectPackerFallsBackToGenericHeuristic(t *testing.T) {
+	f := &File{
+		Anomalies: []string{},
+		Sections: []Section{
+			{Header: sectionNamed(".text").Header, Entropy: floatPtr(7.9)},
+			{Header: sectionNamed(".data").Header, Entropy: floatPtr(7.6)},
+		},
+	}
+
+	matches, err := f.DetectPacker()
+	if err != nil {
+		t.Fatalf("DetectPacker() error = %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected no signature matches, got: %+v", matches)
+	}
+
+	found := false
+	for _, a := range f.Anomalies {
+		if strings.Contains(a, "High proportion of sections with elevated entropy") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the generic entropy-proportion fallback to fire, got: %v", f.Anomalies)
+	}
+}
+
+func TestDetectPackerNoMatchNoElevatedEntropy(t *testing.T) {
+	f := &File{
+		Anomalies: []string{},
+		Sections: []Section{
+			{Header: sectionNamed(".text").Header, Entropy: floatPtr(5.0)},
+		},
+	}
+
+	matches, err := f.DetectPacker()
+	if err != nil {
+		t.Fatalf("DetectPacker() error = %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got: %+v", matches)
+	}
+	if len(f.Anomalies) != 0 {
+		t.Errorf("expected no anomalies, got: %v", f.Anomalies)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package packerdb
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// bySectionName builds a Detect func that flags any section whose name
+// exactly matches one of names, attributing packerName at confidence.
+func bySectionName(packerName string, confidence float64, names ...string) func(f Target) []Match {
+	return func(f Target) []Match {
+		var out []Match
+		for _, sec := range f.Sections() {
+			for _, want := range names {
+				if sec.Name == want {
+					out = append(out, Match{
+						Name:       packerName,
+						Confidence: confidence,
+						Evidence:   fmt.Sprintf("section name %q", sec.Name),
+					})
+				}
+			}
+		}
+		return out
+	}
+}
+
+// entryPointScanSize is how many bytes past the entry point are scanned for
+// a packer stub's magic tag.
+const entryPointScanSize = 256
+
+func init() {
+	RegisterSignature(Signature{Name: "UPX", Detect: detectUPX})
+	RegisterSignature(Signature{Name: "ASPack", Detect: bySectionName("ASPack", 0.85, ".aspack", ".adata")})
+	RegisterSignature(Signature{Name: "MPRESS", Detect: bySectionName("MPRESS", 0.85, "MPRESS1", "MPRESS2")})
+	RegisterSignature(Signature{Name: "PECompact", Detect: bySectionName("PECompact", 0.8, ".pec1")})
+	RegisterSignature(Signature{Name: "Themida/WinLicense", Detect: bySectionName("Themida/WinLicense", 0.8, ".themida", ".winlice")})
+	RegisterSignature(Signature{Name: "Enigma", Detect: bySectionName("Enigma", 0.75, ".enigma1")})
+	RegisterSignature(Signature{Name: "Petite", Detect: bySectionName("Petite", 0.75, ".petite")})
+	RegisterSignature(Signature{Name: "FSG", Detect: detectFSG})
+}
+
+// detectUPX matches on UPX's well-known section names and, when the entry
+// point is reachable, on the "UPX!" stub tag it writes right before a
+// major/minor version byte pair, which lets the version be reported.
+func detectUPX(f Target) []Match {
+	var out []Match
+	for _, sec := range f.Sections() {
+		switch sec.Name {
+		case "UPX0", "UPX1", "UPX2", "UPX!":
+			out = append(out, Match{
+				Name:       "UPX",
+				Confidence: 0.9,
+				Evidence:   fmt.Sprintf("section name %q", sec.Name),
+			})
+		}
+	}
+
+	rva := f.EntryPointRVA()
+	if rva == 0 {
+		return out
+	}
+	data, err := f.ReadData(rva, entryPointScanSize)
+	if err != nil {
+		return out
+	}
+	if idx := bytes.Index(data, []byte("UPX!")); idx >= 0 && idx+6 <= len(data) {
+		major, minor := data[idx+4], data[idx+5]
+		out = append(out, Match{
+			Name:       "UPX",
+			Version:    fmt.Sprintf("%d.%d", major, minor),
+			Confidence: 0.95,
+			Evidence:   `entry-point byte pattern "UPX!"`,
+		})
+	}
+	return out
+}
+
+// detectFSG matches on the "FSG!" tag FSG's stub writes near the entry
+// point.
+func detectFSG(f Target) []Match {
+	rva := f.EntryPointRVA()
+	if rva == 0 {
+		return nil
+	}
+	data, err := f.ReadData(rva, entryPointScanSize)
+	if err != nil {
+		return nil
+	}
+	if bytes.Contains(data, []byte("FSG!")) {
+		return []Match{{
+			Name:       "FSG",
+			Confidence: 0.85,
+			Evidence:   `entry-point byte pattern "FSG!"`,
+		}}
+	}
+	return nil
+}
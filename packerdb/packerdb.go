@@ -0,0 +1,77 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+// Package packerdb holds packer-family signatures (section names,
+// entry-point byte patterns) used by File.DetectPacker. Target exposes no
+// import table, so signatures cannot inspect imports.
+// Built-in signatures are registered the same way a user-supplied one would
+// be, via RegisterSignature, so neither is privileged over the other.
+//
+// This package intentionally does not import github.com/saferwall/pe:
+// package pe calls into Run to evaluate the registry, so a dependency in
+// the other direction would be an import cycle. Signatures are evaluated
+// instead against the Target interface below, which package pe satisfies
+// with a thin adapter over *pe.File.
+package packerdb
+
+// SectionView is the subset of a PE section's data a Signature can inspect.
+type SectionView struct {
+	Name string
+}
+
+// Target is the minimal view of a parsed PE file a Signature is evaluated
+// against.
+type Target interface {
+	Sections() []SectionView
+	EntryPointRVA() uint32
+	ReadData(rva uint32, size int) ([]byte, error)
+}
+
+// Match is one packer-family hit produced by a Signature's Detect func.
+type Match struct {
+	// Name is the packer family, e.g. "UPX" or "ASPack".
+	Name string
+	// Version is the packer version where derivable from its evidence, or
+	// empty when it can't be determined from the signature alone.
+	Version string
+	// Confidence is how sure this signature is of the match, 0..1.
+	Confidence float64
+	// Evidence describes what triggered the match, e.g. a section name, an
+	// entry-point byte pattern, or an import-table shape.
+	Evidence string
+}
+
+// Signature identifies one packer family. Detect is handed the parsed file
+// and returns zero or more Matches; it must not mutate the file.
+type Signature struct {
+	Name   string
+	Detect func(f Target) []Match
+}
+
+var registry []Signature
+
+// RegisterSignature adds s to the set of signatures DetectPacker runs, and
+// is meant to be called from a signature file's init() so built-ins and
+// user-registered signatures share one code path.
+func RegisterSignature(s Signature) {
+	registry = append(registry, s)
+}
+
+// Registered returns the currently registered signatures, built-in and
+// user-supplied alike.
+func Registered() []Signature {
+	out := make([]Signature, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// Run evaluates every registered signature against f and concatenates their
+// matches in registration order.
+func Run(f Target) []Match {
+	var out []Match
+	for _, s := range registry {
+		out = append(out, s.Detect(f)...)
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package packerdb
+
+import "testing"
+
+// testTarget is a bare-bones Target implementation for exercising
+// signatures without going through package pe's adapter.
+type testTarget struct {
+	sections []SectionView
+}
+
+func (t testTarget) Sections() []SectionView { return t.sections }
+func (t testTarget) EntryPointRVA() uint32   { return 0 }
+func (t testTarget) ReadData(rva uint32, size int) ([]byte, error) {
+	return nil, nil
+}
+
+func TestRegisterSignatureIsRunByRun(t *testing.T) {
+	before := len(Registered())
+
+	RegisterSignature(Signature{
+		Name: "CustomPacker",
+		Detect: func(f Target) []Match {
+			return []Match{{Name: "CustomPacker", Confidence: 1}}
+		},
+	})
+
+	if len(Registered()) != before+1 {
+		t.Fatalf("Registered() len = %d, want %d", len(Registered()), before+1)
+	}
+
+	matches := Run(testTarget{})
+	found := false
+	for _, m := range matches {
+		if m.Name == "CustomPacker" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected CustomPacker in Run() output, got: %+v", matches)
+	}
+}
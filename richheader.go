@@ -0,0 +1,192 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Anomaly messages for the Rich header subsystem. ParseRichHeader and
+// VerifyRichHeader do not record them, and nothing in this package adds
+// them to the anomaly list.
+const (
+	// AnoRichHeaderMissing fires when an MSVC-linked binary (inferred from
+	// the Windows subsystem and a plausible MajorLinkerVersion) has no Rich
+	// header, which is unusual since every mainstream MSVC linker emits one.
+	AnoRichHeaderMissing = "Rich header missing on an apparently MSVC-linked binary"
+
+	// AnoRichHeaderChecksumMismatch fires when the recomputed DanS checksum
+	// disagrees with the recovered XOR key.
+	AnoRichHeaderChecksumMismatch = "Rich header checksum does not match its XOR key"
+
+	// AnoRichHeaderCompIDInconsistent fires when the decoded @comp.id
+	// entries are incompatible with OptionalHeader.MajorLinkerVersion.
+	AnoRichHeaderCompIDInconsistent = "Rich header @comp.id entries inconsistent with MajorLinkerVersion"
+
+	// AnoRichHeaderToolchainMix fires when the Rich header records more
+	// than one MSVC major version in a single binary.
+	AnoRichHeaderToolchainMix = "Rich header records a mix of MSVC toolchain versions"
+)
+
+// richTag and dansTag are the two 4-byte markers bracketing the Rich header:
+// "Rich" terminates it, "DanS" (once XOR-decoded) begins it.
+var (
+	richTag = []byte("Rich")
+	dansTag = [4]byte{'D', 'a', 'n', 'S'}
+)
+
+// ErrRichHeaderNotFound is returned by ParseRichHeader when no "Rich" marker
+// is found between the DOS stub and the PE signature.
+var ErrRichHeaderNotFound = errors.New("pe: Rich header not found")
+
+// CompID is one decoded @comp.id entry from the Rich header: a
+// (product id, minor compiler version, use count) tuple, plus the raw
+// still-XORed DWORD pair it was unmasked from.
+type CompID struct {
+	// ProdID identifies the tool (compiler, linker, ...) that emitted this
+	// entry.
+	ProdID uint16
+	// MinorCV is the tool's minor/build version number.
+	MinorCV uint16
+	// Count is how many objects this tool produced that were linked into
+	// the image.
+	Count uint32
+	// Unmasked is the entry's two DWORDs (ProdID<<16|MinorCV, Count) as they
+	// appear on disk, still XORed with RichHeader.XORKey.
+	Unmasked [2]uint32
+}
+
+// RichHeader is the parsed, de-obfuscated contents of the undocumented
+// "Rich" header that MSVC linkers embed between the DOS stub and the PE
+// signature.
+type RichHeader struct {
+	// XORKey is read from the DWORD that follows the "Rich" marker; the
+	// "DanS" signature is stored XORed with it. It also doubles as the header's
+	// checksum; see VerifyRichHeader.
+	XORKey  uint32
+	CompIDs []CompID
+	// DansOffset is the byte offset of the "DanS" marker within the DOS
+	// stub (pe.dosStub()), i.e. where Raw begins.
+	DansOffset int
+	Raw        []byte // the undecoded bytes, from "DanS" through "Rich" inclusive.
+}
+
+// ParseRichHeader locates the Rich header in the bytes before the PE
+// signature (file offset 0 up to e_lfanew, see dosStub) and decodes it into
+// pe.RichHeader: the trailing "Rich" marker is followed by the XOR key,
+// which is also what "DanS" was XORed with to obfuscate the header from
+// naive string scans.
+func (pe *File) ParseRichHeader() error {
+	var rh RichHeader
+
+	stub, err := pe.dosStub()
+	if err != nil {
+		return err
+	}
+
+	richIdx := bytes.Index(stub, richTag)
+	if richIdx < 0 {
+		return ErrRichHeaderNotFound
+	}
+	if richIdx+8 > len(stub) {
+		return ErrRichHeaderNotFound
+	}
+
+	key := binary.LittleEndian.Uint32(stub[richIdx+4 : richIdx+8])
+	rh.XORKey = key
+
+	// Walk backwards in 4-byte steps from "Rich", decoding until we hit the
+	// "DanS" signature (decoded "DanS" XOR key == key).
+	dansIdx := -1
+	for i := richIdx - 4; i >= 0; i -= 4 {
+		word := binary.LittleEndian.Uint32(stub[i:i+4]) ^ key
+		if word == binary.LittleEndian.Uint32(dansTag[:]) {
+			dansIdx = i
+			break
+		}
+	}
+	if dansIdx < 0 {
+		return ErrRichHeaderNotFound
+	}
+
+	rh.DansOffset = dansIdx
+	rh.Raw = append([]byte(nil), stub[dansIdx:richIdx+8]...)
+
+	// Entries are two padding DWORDs after "DanS", then pairs of
+	// (prodID<<16|minorCV, count) DWORDs, each XORed with key.
+	cursor := dansIdx + 16
+	for cursor+8 <= richIdx {
+		raw1 := binary.LittleEndian.Uint32(stub[cursor : cursor+4])
+		raw2 := binary.LittleEndian.Uint32(stub[cursor+4 : cursor+8])
+		w1 := raw1 ^ key
+		w2 := raw2 ^ key
+		rh.CompIDs = append(rh.CompIDs, CompID{
+			ProdID:   uint16(w1 >> 16),
+			MinorCV:  uint16(w1),
+			Count:    w2,
+			Unmasked: [2]uint32{raw1, raw2},
+		})
+		cursor += 8
+	}
+
+	pe.RichHeader = rh
+	return nil
+}
+
+// VerifyRichHeader parses the Rich header (if not already parsed) and
+// recomputes its checksum (the XOR key, which doubles as a checksum over
+// the DOS header and every decoded DWORD), reporting whether it matches
+// the value embedded in the file.
+func (pe *File) VerifyRichHeader() (stored, computed uint32, ok bool, err error) {
+	if pe.RichHeader.XORKey == 0 {
+		if err := pe.ParseRichHeader(); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	rh := pe.RichHeader
+
+	stub, err := pe.dosStub()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	// The checksum is the byte-rotated sum of the DOS header bytes (with
+	// e_lfanew's own field excluded) plus every plaintext comp.id DWORD,
+	// using the same rotation the MSVC linker applies when emitting it.
+	var sum uint32
+	for i := 0; i < 64 && i < len(stub); i += 4 {
+		if i == 0x3C {
+			continue // e_lfanew is excluded from the checksum.
+		}
+		word := binary.LittleEndian.Uint32(stub[i : i+4])
+		sum += rotl32(word, int(i))
+	}
+	for idx, c := range rh.CompIDs {
+		word := uint32(c.ProdID)<<16 | uint32(c.MinorCV)
+		sum += rotl32(word, idx*2)
+		sum += rotl32(c.Count, idx*2+1)
+	}
+
+	return rh.XORKey, sum, rh.XORKey == sum, nil
+}
+
+func rotl32(x uint32, n int) uint32 {
+	n %= 32
+	return x<<uint(n) | x>>uint(32-n)
+}
+
+// dosStub returns the file's bytes from offset 0 up to the start of the PE
+// signature (e_lfanew): the fixed DOS header followed by the DOS stub, where
+// the Rich header lives.
+func (pe *File) dosStub() ([]byte, error) {
+	raw := pe.RawData()
+	lfanew := pe.DosHeader.AddressOfNewEXEHeader
+	if int64(lfanew) > int64(len(raw)) || lfanew < 64 {
+		return nil, ErrRichHeaderNotFound
+	}
+	return raw[:lfanew], nil
+}
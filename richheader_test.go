@@ -0,0 +1,104 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildRichStub synthesizes a minimal DOS header + Rich header as it would
+// appear in the DOS stub region, for the given plaintext comp.id entries.
+func buildRichStub(key uint32, comps []CompID) []byte {
+	stub := make([]byte, 64)
+	stub[0], stub[1] = 'M', 'Z'
+	binary.LittleEndian.PutUint32(stub[0x3C:0x40], uint32(len(stub)+8*(2+len(comps))+8))
+
+	var sum uint32
+	for i := 0; i < 64; i += 4 {
+		if i == 0x3C {
+			continue
+		}
+		sum += rotl32(binary.LittleEndian.Uint32(stub[i:i+4]), i)
+	}
+	for idx, c := range comps {
+		word := uint32(c.ProdID)<<16 | uint32(c.MinorCV)
+		sum += rotl32(word, idx*2)
+		sum += rotl32(c.Count, idx*2+1)
+	}
+	key = sum // make the fixture self-consistent: XOR key == checksum.
+
+	enc := func(v uint32) []byte {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, v^key)
+		return b
+	}
+
+	buf := append([]byte{}, stub...)
+	buf = append(buf, enc(binary.LittleEndian.Uint32(dansTag[:]))...)
+	buf = append(buf, enc(0)...)
+	buf = append(buf, enc(0)...)
+	for _, c := range comps {
+		word := uint32(c.ProdID)<<16 | uint32(c.MinorCV)
+		buf = append(buf, enc(word)...)
+		buf = append(buf, enc(c.Count)...)
+	}
+	buf = append(buf, richTag...)
+	keyBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(keyBytes, key)
+	buf = append(buf, keyBytes...)
+
+	return buf
+}
+
+func fileWithDosStub(stub []byte) *File {
+	return &File{
+		Anomalies: []string{},
+		DosHeader: ImageDOSHeader{AddressOfNewEXEHeader: uint32(len(stub))},
+		data:      stub,
+	}
+}
+
+func TestParseRichHeader(t *testing.T) {
+	comps := []CompID{{ProdID: 0x0095, MinorCV: 0x7809, Count: 3}}
+	stub := buildRichStub(0, comps)
+	f := fileWithDosStub(stub)
+
+	if err := f.ParseRichHeader(); err != nil {
+		t.Fatalf("ParseRichHeader() error = %v", err)
+	}
+	rh := f.RichHeader
+	if len(rh.CompIDs) != 1 {
+		t.Fatalf("CompIDs = %+v, want 1 entry", rh.CompIDs)
+	}
+	got := rh.CompIDs[0]
+	if got.ProdID != comps[0].ProdID || got.MinorCV != comps[0].MinorCV || got.Count != comps[0].Count {
+		t.Errorf("CompIDs[0] = %+v, want %+v", got, comps[0])
+	}
+}
+
+func TestParseRichHeaderMissing(t *testing.T) {
+	stub := make([]byte, 64)
+	f := fileWithDosStub(stub)
+
+	err := f.ParseRichHeader()
+	if err != ErrRichHeaderNotFound {
+		t.Errorf("err = %v, want ErrRichHeaderNotFound", err)
+	}
+}
+
+func TestVerifyRichHeader(t *testing.T) {
+	comps := []CompID{{ProdID: 0x0095, MinorCV: 0x7809, Count: 3}}
+	stub := buildRichStub(0, comps)
+	f := fileWithDosStub(stub)
+
+	stored, computed, ok, err := f.VerifyRichHeader()
+	if err != nil {
+		t.Fatalf("VerifyRichHeader() error = %v", err)
+	}
+	if !ok || stored != computed {
+		t.Errorf("VerifyRichHeader() stored=0x%x computed=0x%x ok=%v", stored, computed, ok)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ImageROMOptionalHeaderMagic identifies the IMAGE_ROM_OPTIONAL_HEADER
+// layout, used by legacy ROM images and some EFI firmware binaries,
+// distinct from the PE32/PE32+ optional header magics.
+const ImageROMOptionalHeaderMagic = 0x107
+
+// ROMOptionalHeaderSize is the fixed, on-disk size of IMAGE_ROM_OPTIONAL_HEADER.
+const ROMOptionalHeaderSize = 2 + 2 + 4*7 + 4 + 4*4 + 4
+
+// ROMOptionalHeader is IMAGE_ROM_OPTIONAL_HEADER: the optional header
+// variant selected when the optional header's Magic is
+// ImageROMOptionalHeaderMagic. It carries none of the PE32/PE32+ loader
+// fields (no ImageBase, no data directories) since ROM images aren't
+// relocated or loaded by the Windows PE loader the way PE32/PE32+ images
+// are.
+type ROMOptionalHeader struct {
+	Magic                   uint16
+	MajorLinkerVersion      uint8
+	MinorLinkerVersion      uint8
+	SizeOfCode              uint32
+	SizeOfInitializedData   uint32
+	SizeOfUninitializedData uint32
+	AddressOfEntryPoint     uint32
+	BaseOfCode              uint32
+	BaseOfData              uint32
+	BaseOfBss               uint32
+	GprMask                 uint32
+	CprMask                 [4]uint32
+	GpValue                 uint32
+}
+
+// EFISubsystemKind narrows the generic Subsystem field down to the four
+// EFI subsystem subtypes a firmware image can declare.
+type EFISubsystemKind int
+
+// EFI subsystem subtypes returned by File.EFISubsystemKind.
+const (
+	EFINotEFI EFISubsystemKind = iota
+	EFIApplicationKind
+	EFIBootServiceDriverKind
+	EFIRuntimeDriverKind
+	EFIROMKind
+)
+
+// String implements fmt.Stringer for EFISubsystemKind.
+func (k EFISubsystemKind) String() string {
+	switch k {
+	case EFIApplicationKind:
+		return "EFI Application"
+	case EFIBootServiceDriverKind:
+		return "EFI Boot Service Driver"
+	case EFIRuntimeDriverKind:
+		return "EFI Runtime Driver"
+	case EFIROMKind:
+		return "EFI ROM"
+	default:
+		return "not EFI"
+	}
+}
+
+// EFISubsystemKind classifies the image's subsystem into one of the four
+// EFI subtypes, or EFINotEFI when the image isn't an EFI image at all.
+func (pe *File) EFISubsystemKind() EFISubsystemKind {
+	switch pe.subsystem() {
+	case ImageSubsystemEFIApplication:
+		return EFIApplicationKind
+	case ImageSubsystemEFIBootServiceDriver:
+		return EFIBootServiceDriverKind
+	case ImageSubsystemEFIRuntimeDriver:
+		return EFIRuntimeDriverKind
+	case ImageSubsystemEFIRom:
+		return EFIROMKind
+	default:
+		return EFINotEFI
+	}
+}
+
+// decodeROMOptionalHeader is the pure decoder behind parseROMOptionalHeader:
+// it takes the already-resolved optional header bytes and returns the
+// decoded struct, without touching the File so it can be exercised
+// directly in tests.
+func decodeROMOptionalHeader(data []byte) (ROMOptionalHeader, error) {
+	var oh ROMOptionalHeader
+	if len(data) < ROMOptionalHeaderSize {
+		return oh, fmt.Errorf("ROM optional header truncated: got %d bytes, want %d", len(data), ROMOptionalHeaderSize)
+	}
+
+	oh.Magic = binary.LittleEndian.Uint16(data[0:2])
+	oh.MajorLinkerVersion = data[2]
+	oh.MinorLinkerVersion = data[3]
+	oh.SizeOfCode = binary.LittleEndian.Uint32(data[4:8])
+	oh.SizeOfInitializedData = binary.LittleEndian.Uint32(data[8:12])
+	oh.SizeOfUninitializedData = binary.LittleEndian.Uint32(data[12:16])
+	oh.AddressOfEntryPoint = binary.LittleEndian.Uint32(data[16:20])
+	oh.BaseOfCode = binary.LittleEndian.Uint32(data[20:24])
+	oh.BaseOfData = binary.LittleEndian.Uint32(data[24:28])
+	oh.BaseOfBss = binary.LittleEndian.Uint32(data[28:32])
+	oh.GprMask = binary.LittleEndian.Uint32(data[32:36])
+	for i := 0; i < 4; i++ {
+		off := 36 + i*4
+		oh.CprMask[i] = binary.LittleEndian.Uint32(data[off : off+4])
+	}
+	oh.GpValue = binary.LittleEndian.Uint32(data[52:56])
+
+	return oh, nil
+}
+
+// parseROMOptionalHeader decodes data (the optional header bytes, already
+// resolved by the directory-agnostic header parser once it sees Magic ==
+// ImageROMOptionalHeaderMagic) and stores the result as pe.NtHeader's
+// optional header, then runs the EFI-specific validators since ROM-magic
+// optional headers are how this parser recognizes EFI firmware images.
+func (pe *File) parseROMOptionalHeader(data []byte) error {
+	oh, err := decodeROMOptionalHeader(data)
+	if err != nil {
+		return err
+	}
+	pe.NtHeader.OptionalHeader = oh
+
+	for _, msg := range pe.validateEFIImage(oh) {
+		pe.addAnomaly(msg)
+	}
+	return nil
+}
+
+// validateEFIImage runs the EFI-specific structural checks a firmware image
+// should satisfy, returning one anomaly message per violation. The entry
+// point alignment check applies to any ROM-magic image on an
+// architecture that requires it. The .reloc-section check for runtime
+// drivers only fires when pe.EFISubsystemKind can actually tell a Runtime
+// Driver apart from the other EFI subtypes, which requires the Subsystem
+// field from a PE32/PE32+ optional header -- IMAGE_ROM_OPTIONAL_HEADER
+// carries no Subsystem field of its own, so a pure ROM-magic image skips
+// that check.
+func (pe *File) validateEFIImage(oh ROMOptionalHeader) []string {
+	var anomalies []string
+
+	machine := pe.machine()
+	if oh.AddressOfEntryPoint != 0 && (machine.IsARM() || machine.IsRISCV() || machine.IsEFIByteCode()) {
+		if oh.AddressOfEntryPoint%4 != 0 {
+			anomalies = append(anomalies, fmt.Sprintf(
+				"EFI image entry point 0x%x is not 4-byte aligned, required for %s", oh.AddressOfEntryPoint, machine.String()))
+		}
+	}
+
+	if pe.EFISubsystemKind() == EFIRuntimeDriverKind && !pe.hasSection(".reloc") {
+		anomalies = append(anomalies, "EFI Runtime Driver is missing a .reloc section, required so the OS can relocate it at runtime")
+	}
+
+	return anomalies
+}
+
+// hasSection reports whether any section's name matches want, case-insensitively.
+func (pe *File) hasSection(want string) bool {
+	for _, sec := range pe.Sections {
+		if strings.EqualFold(sec.NameString(), want) {
+			return true
+		}
+	}
+	return false
+}
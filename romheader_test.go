@@ -0,0 +1,119 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func buildROMOptionalHeader(entryPoint uint32) []byte {
+	data := make([]byte, ROMOptionalHeaderSize)
+	binary.LittleEndian.PutUint16(data[0:2], ImageROMOptionalHeaderMagic)
+	data[2], data[3] = 9, 0
+	binary.LittleEndian.PutUint32(data[16:20], entryPoint)
+	return data
+}
+
+func TestDecodeROMOptionalHeader(t *testing.T) {
+	data := buildROMOptionalHeader(0x1000)
+	oh, err := decodeROMOptionalHeader(data)
+	if err != nil {
+		t.Fatalf("decodeROMOptionalHeader() error = %v", err)
+	}
+	if oh.Magic != ImageROMOptionalHeaderMagic {
+		t.Errorf("Magic = 0x%x, want 0x%x", oh.Magic, ImageROMOptionalHeaderMagic)
+	}
+	if oh.AddressOfEntryPoint != 0x1000 {
+		t.Errorf("AddressOfEntryPoint = 0x%x, want 0x1000", oh.AddressOfEntryPoint)
+	}
+}
+
+func TestDecodeROMOptionalHeaderTruncated(t *testing.T) {
+	if _, err := decodeROMOptionalHeader(make([]byte, 8)); err == nil {
+		t.Error("expected an error for a truncated ROM optional header, got nil")
+	}
+}
+
+func TestEFISubsystemKind(t *testing.T) {
+	tests := []struct {
+		name      string
+		subsystem ImageOptionalHeaderSubsystemType
+		want      EFISubsystemKind
+	}{
+		{"Application", ImageSubsystemEFIApplication, EFIApplicationKind},
+		{"BootServiceDriver", ImageSubsystemEFIBootServiceDriver, EFIBootServiceDriverKind},
+		{"RuntimeDriver", ImageSubsystemEFIRuntimeDriver, EFIRuntimeDriverKind},
+		{"ROM", ImageSubsystemEFIRom, EFIROMKind},
+		{"WindowsGUI", ImageSubsystemWindowsGUI, EFINotEFI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{NtHeader: ImageNtHeader{OptionalHeader: ImageOptionalHeader64{Subsystem: tt.subsystem}}}
+			if got := f.EFISubsystemKind(); got != tt.want {
+				t.Errorf("EFISubsystemKind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEFIImageFlagsMisalignedEntryPointOnARM(t *testing.T) {
+	f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineARM64}}}
+	oh := ROMOptionalHeader{AddressOfEntryPoint: 0x1001}
+
+	anomalies := f.validateEFIImage(oh)
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d: %v", len(anomalies), anomalies)
+	}
+}
+
+func TestValidateEFIImageAllowsAlignedEntryPoint(t *testing.T) {
+	f := &File{NtHeader: ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineARM64}}}
+	oh := ROMOptionalHeader{AddressOfEntryPoint: 0x1000}
+
+	if anomalies := f.validateEFIImage(oh); len(anomalies) != 0 {
+		t.Errorf("unexpected anomalies: %v", anomalies)
+	}
+}
+
+func TestValidateEFIImageRequiresRelocForRuntimeDriver(t *testing.T) {
+	f := &File{
+		NtHeader: ImageNtHeader{
+			FileHeader:     ImageFileHeader{Machine: ImageFileMachineAMD64},
+			OptionalHeader: ImageOptionalHeader64{Subsystem: ImageSubsystemEFIRuntimeDriver},
+		},
+		Sections: []Section{
+			{Header: ImageSectionHeader{Name: [8]uint8{'.', 't', 'e', 'x', 't', 0, 0, 0}}},
+		},
+	}
+
+	anomalies := f.validateEFIImage(ROMOptionalHeader{})
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly for missing .reloc, got %d: %v", len(anomalies), anomalies)
+	}
+}
+
+func TestParseROMOptionalHeaderSetsOptionalHeaderAndValidates(t *testing.T) {
+	f := &File{
+		NtHeader:  ImageNtHeader{FileHeader: ImageFileHeader{Machine: ImageFileMachineARM64}},
+		Anomalies: []string{},
+	}
+	data := buildROMOptionalHeader(0x1003)
+
+	if err := f.parseROMOptionalHeader(data); err != nil {
+		t.Fatalf("parseROMOptionalHeader() error = %v", err)
+	}
+	oh, ok := f.NtHeader.OptionalHeader.(ROMOptionalHeader)
+	if !ok {
+		t.Fatalf("OptionalHeader is %T, want ROMOptionalHeader", f.NtHeader.OptionalHeader)
+	}
+	if oh.AddressOfEntryPoint != 0x1003 {
+		t.Errorf("AddressOfEntryPoint = 0x%x, want 0x1003", oh.AddressOfEntryPoint)
+	}
+	if len(f.Anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly for misaligned entry point, got %d: %v", len(f.Anomalies), f.Anomalies)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors specific to NewReader's lightweight header parse.
+var (
+	errStreamDosMagicNotFound    = errors.New(`pe: DOS header magic "MZ" not found`)
+	errStreamPESignatureNotFound = errors.New("pe: PE signature not found at e_lfanew")
+)
+
+// defaultCacheBytes is used when Options.Streaming is set but
+// Options.CacheBytes is left at its zero value.
+const defaultCacheBytes = 16 * 1024 * 1024
+
+// streamCacheEntry is one cached directory's bytes, in recency order via
+// streamCache.order.
+type streamCacheEntry struct {
+	key  ImageDirectoryEntry
+	data []byte
+}
+
+// streamCache is a byte-budget-bounded LRU cache of directory contents,
+// keyed by directory entry, used by streaming-mode Files so that repeated
+// ParseDirectory calls for the same entry don't re-fault through the
+// io.ReaderAt.
+type streamCache struct {
+	capBytes int64
+	used     int64
+	entries  map[ImageDirectoryEntry]int // key -> index into order
+	order    []streamCacheEntry          // index 0 = least recently used
+}
+
+func newStreamCache(capBytes int64) *streamCache {
+	if capBytes <= 0 {
+		capBytes = defaultCacheBytes
+	}
+	return &streamCache{
+		capBytes: capBytes,
+		entries:  make(map[ImageDirectoryEntry]int),
+	}
+}
+
+func (c *streamCache) get(key ImageDirectoryEntry) ([]byte, bool) {
+	idx, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := c.order[idx]
+	c.touch(idx)
+	return entry.data, true
+}
+
+// touch moves the entry at idx to the most-recently-used end.
+func (c *streamCache) touch(idx int) {
+	entry := c.order[idx]
+	c.order = append(c.order[:idx], c.order[idx+1:]...)
+	c.order = append(c.order, entry)
+	for i, e := range c.order {
+		c.entries[e.key] = i
+	}
+}
+
+func (c *streamCache) put(key ImageDirectoryEntry, data []byte) {
+	if idx, ok := c.entries[key]; ok {
+		c.used -= int64(len(c.order[idx].data))
+		c.order = append(c.order[:idx], c.order[idx+1:]...)
+		for i, e := range c.order {
+			c.entries[e.key] = i
+		}
+	}
+
+	for c.used+int64(len(data)) > c.capBytes && len(c.order) > 0 {
+		evicted := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, evicted.key)
+		c.used -= int64(len(evicted.data))
+	}
+
+	c.order = append(c.order, streamCacheEntry{key: key, data: data})
+	c.entries[key] = len(c.order) - 1
+	c.used += int64(len(data))
+}
+
+// NewReader parses just enough of r (the DOS header, NT headers, and
+// section table) to populate File.Sections, e_lfanew in File.DosHeader,
+// SizeOfOptionalHeader in File.NtHeader.FileHeader and the optional header
+// fields parseStreamingOptionalHeader reads; every other header field is
+// left at zero. It reads no section or data-directory bytes. Those are
+// instead faulted in lazily through r by ParseDirectory, and cached in an
+// LRU bounded by Options.CacheBytes (defaultCacheBytes if unset), so callers
+// scanning very large images can stop after inspecting only the directories
+// they care about.
+func NewReader(r io.ReaderAt, size int64, opts *Options) (*File, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	opts.Streaming = true
+
+	header := make([]byte, 0x40)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("reading DOS header: %w", err)
+	}
+	if header[0] != 'M' || header[1] != 'Z' {
+		return nil, errStreamDosMagicNotFound
+	}
+	lfanew := int64(binary.LittleEndian.Uint32(header[0x3C:0x40]))
+
+	fileHdr := make([]byte, 4+imageFileHeaderSize)
+	if _, err := r.ReadAt(fileHdr, lfanew); err != nil {
+		return nil, fmt.Errorf("reading NT/file header: %w", err)
+	}
+	if string(fileHdr[0:4]) != "PE\x00\x00" {
+		return nil, errStreamPESignatureNotFound
+	}
+
+	numSections := binary.LittleEndian.Uint16(fileHdr[4+2 : 4+4])
+	sizeOfOptionalHeader := binary.LittleEndian.Uint16(fileHdr[4+16 : 4+18])
+
+	optHdr := make([]byte, sizeOfOptionalHeader)
+	if sizeOfOptionalHeader > 0 {
+		if _, err := r.ReadAt(optHdr, lfanew+4+imageFileHeaderSize); err != nil {
+			return nil, fmt.Errorf("reading optional header: %w", err)
+		}
+	}
+	optionalHeader := parseStreamingOptionalHeader(optHdr)
+
+	sectionTableOffset := lfanew + 4 + imageFileHeaderSize + int64(sizeOfOptionalHeader)
+	const sectionHeaderSize = 40
+	sections := make([]Section, 0, numSections)
+	for i := uint16(0); i < numSections; i++ {
+		buf := make([]byte, sectionHeaderSize)
+		off := sectionTableOffset + int64(i)*sectionHeaderSize
+		if _, err := r.ReadAt(buf, off); err != nil {
+			break
+		}
+		var hdr ImageSectionHeader
+		copy(hdr.Name[:], buf[0:8])
+		hdr.VirtualSize = binary.LittleEndian.Uint32(buf[8:12])
+		hdr.VirtualAddress = binary.LittleEndian.Uint32(buf[12:16])
+		hdr.SizeOfRawData = binary.LittleEndian.Uint32(buf[16:20])
+		hdr.PointerToRawData = binary.LittleEndian.Uint32(buf[20:24])
+		hdr.Characteristics = binary.LittleEndian.Uint32(buf[36:40])
+		sections = append(sections, Section{Header: hdr})
+	}
+
+	return &File{
+		Anomalies: []string{},
+		Sections:  sections,
+		DosHeader: ImageDOSHeader{AddressOfNewEXEHeader: uint32(lfanew)},
+		NtHeader: ImageNtHeader{
+			FileHeader:     ImageFileHeader{SizeOfOptionalHeader: sizeOfOptionalHeader},
+			OptionalHeader: optionalHeader,
+		},
+		opts:   opts,
+		reader: r,
+		size:   size,
+		cache:  newStreamCache(int64(opts.CacheBytes)),
+	}, nil
+}
+
+// imageNtOptionalHdr32Magic and imageNtOptionalHdr64Magic are the Magic
+// field values distinguishing PE32 from PE32+.
+const (
+	imageNtOptionalHdr32Magic = 0x10b
+	imageNtOptionalHdr64Magic = 0x20b
+)
+
+// parseStreamingOptionalHeader reads just the Magic, NumberOfRvaAndSizes,
+// and DataDirectory array out of optHdr -- enough for GetDataDirectory to
+// work against a NewReader-opened File -- leaving every other optional
+// header field at its zero value.
+func parseStreamingOptionalHeader(optHdr []byte) interface{} {
+	if len(optHdr) < 2 {
+		return nil
+	}
+	magic := binary.LittleEndian.Uint16(optHdr[0:2])
+
+	switch magic {
+	case imageNtOptionalHdr64Magic:
+		if len(optHdr) < ImageOptionalHeader64FixedSize {
+			return ImageOptionalHeader64{}
+		}
+		var oh ImageOptionalHeader64
+		oh.NumberOfRvaAndSizes = binary.LittleEndian.Uint32(optHdr[108:112])
+		n := len(optHdr[ImageOptionalHeader64FixedSize:]) / 8
+		if uint32(n) > oh.NumberOfRvaAndSizes {
+			n = int(oh.NumberOfRvaAndSizes)
+		}
+		oh.DataDirectory = make([]DataDirectory, n)
+		readStreamingDataDirectories(optHdr[ImageOptionalHeader64FixedSize:], oh.DataDirectory[:])
+		return oh
+	case imageNtOptionalHdr32Magic:
+		if len(optHdr) < ImageOptionalHeader32FixedSize {
+			return ImageOptionalHeader32{}
+		}
+		var oh ImageOptionalHeader32
+		oh.NumberOfRvaAndSizes = binary.LittleEndian.Uint32(optHdr[92:96])
+		n := len(optHdr[ImageOptionalHeader32FixedSize:]) / 8
+		if uint32(n) > oh.NumberOfRvaAndSizes {
+			n = int(oh.NumberOfRvaAndSizes)
+		}
+		oh.DataDirectory = make([]DataDirectory, n)
+		readStreamingDataDirectories(optHdr[ImageOptionalHeader32FixedSize:], oh.DataDirectory[:])
+		return oh
+	default:
+		return nil
+	}
+}
+
+// readStreamingDataDirectories decodes as many 8-byte {VirtualAddress,Size}
+// entries as fit in both data and dirs.
+func readStreamingDataDirectories(data []byte, dirs []DataDirectory) {
+	for i := range dirs {
+		off := i * 8
+		if off+8 > len(data) {
+			return
+		}
+		dirs[i] = DataDirectory{
+			VirtualAddress: binary.LittleEndian.Uint32(data[off : off+4]),
+			Size:           binary.LittleEndian.Uint32(data[off+4 : off+8]),
+		}
+	}
+}
+
+// ParseDirectory lazily fetches and caches entry's raw bytes through the
+// io.ReaderAt passed to NewReader, then runs whatever directory-specific
+// parsing this package implements for entry (currently the architecture
+// directory; other entries are cached but otherwise left unparsed until
+// their dedicated parsers exist). It is a no-op error on a File not created
+// via NewReader.
+func (pe *File) ParseDirectory(entry ImageDirectoryEntry) error {
+	if pe.reader == nil {
+		return fmt.Errorf("ParseDirectory: file was not opened with NewReader")
+	}
+
+	dir, ok := pe.GetDataDirectory(int(entry))
+	if !ok || dir.Size == 0 {
+		return nil
+	}
+
+	if _, cached := pe.cache.get(entry); !cached {
+		data, err := pe.readDirectoryBytes(dir)
+		if err != nil {
+			return err
+		}
+		pe.cache.put(entry, data)
+	}
+
+	if entry == ImageDirectoryEntryArchitecture {
+		return pe.parseArchitectureDirectory(dir.VirtualAddress, dir.Size)
+	}
+	return nil
+}
+
+// readDirectoryBytes resolves dir's RVA to a file offset via the section
+// table and reads its Size bytes through pe.reader.
+func (pe *File) readDirectoryBytes(dir DataDirectory) ([]byte, error) {
+	offset, err := pe.rvaToFileOffset(dir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, dir.Size)
+	if _, err := pe.reader.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// rvaToFileOffset translates rva to a file offset using the section whose
+// virtual range contains it.
+func (pe *File) rvaToFileOffset(rva uint32) (int64, error) {
+	for _, sec := range pe.Sections {
+		start := sec.Header.VirtualAddress
+		end := start + sec.Header.VirtualSize
+		if rva >= start && rva < end {
+			delta := rva - start
+			return int64(sec.Header.PointerToRawData) + int64(delta), nil
+		}
+	}
+	return 0, fmt.Errorf("rva 0x%x not found in any section", rva)
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Saferwall. All rights reserved.
+// Use of this source code is governed by Apache v2 license
+// license that can be found in the LICENSE file.
+
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildStreamablePE builds a minimal image with numSections sections of
+// sectionDataSize bytes each, sized so NewReader can locate the section
+// table and ParseDirectory can resolve an RVA back to a file offset. The
+// optional header area is left zeroed (no Magic), so tests that need data
+// directories set f.NtHeader themselves.
+func buildStreamablePE(numSections int, sectionDataSize int) []byte {
+	const (
+		lfanew                = 0x80
+		sizeOfOptionalHeader  = ImageOptionalHeader32FixedSize + 16*8
+		sectionHeaderSize     = 40
+		sectionTableOffset    = lfanew + 4 + imageFileHeaderSize + sizeOfOptionalHeader
+		sectionVirtualAddress = 0x1000
+	)
+	sectionsEnd := sectionTableOffset + numSections*sectionHeaderSize
+	pointerToRawData := (sectionsEnd + 0x1FF) &^ 0x1FF // align up, arbitrarily
+
+	data := make([]byte, pointerToRawData+numSections*sectionDataSize)
+	data[0], data[1] = 'M', 'Z'
+	binary.LittleEndian.PutUint32(data[0x3C:0x40], lfanew)
+	copy(data[lfanew:], []byte("PE\x00\x00"))
+
+	fileHdr := data[lfanew+4:]
+	binary.LittleEndian.PutUint16(fileHdr[2:4], uint16(numSections))
+	binary.LittleEndian.PutUint16(fileHdr[16:18], uint16(sizeOfOptionalHeader))
+
+	for i := 0; i < numSections; i++ {
+		off := sectionTableOffset + i*sectionHeaderSize
+		copy(data[off:off+8], []byte("sect"))
+		binary.LittleEndian.PutUint32(data[off+8:off+12], uint32(sectionDataSize))
+		binary.LittleEndian.PutUint32(data[off+12:off+16], uint32(sectionVirtualAddress+i*0x1000))
+		binary.LittleEndian.PutUint32(data[off+16:off+20], uint32(sectionDataSize))
+		binary.LittleEndian.PutUint32(data[off+20:off+24], uint32(pointerToRawData+i*sectionDataSize))
+	}
+
+	return data
+}
+
+func TestNewReaderPopulatesHeaderAndSections(t *testing.T) {
+	data := buildStreamablePE(2, 64)
+
+	f, err := NewReader(bytes.NewReader(data), int64(len(data)), &Options{})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if len(f.Sections) != 2 {
+		t.Fatalf("Sections len = %d, want 2", len(f.Sections))
+	}
+	if !f.opts.Streaming {
+		t.Error("opts.Streaming = false, want true")
+	}
+	if f.reader == nil {
+		t.Error("reader not set")
+	}
+}
+
+func TestNewReaderRejectsBadMagic(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(make([]byte, 64)), 64, &Options{}); err == nil {
+		t.Error("expected an error for a non-MZ header, got nil")
+	}
+}
+
+func TestStreamCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newStreamCache(10)
+	c.put(ImageDirectoryEntryExport, make([]byte, 6))
+	c.put(ImageDirectoryEntryImport, make([]byte, 6))
+
+	if _, ok := c.get(ImageDirectoryEntryExport); ok {
+		t.Error("expected Export entry to have been evicted")
+	}
+	if _, ok := c.get(ImageDirectoryEntryImport); !ok {
+		t.Error("expected Import entry to still be cached")
+	}
+}
+
+func TestStreamCacheGetTouchesEntry(t *testing.T) {
+	c := newStreamCache(10)
+	c.put(ImageDirectoryEntryExport, make([]byte, 5))
+	c.put(ImageDirectoryEntryImport, make([]byte, 4))
+
+	// Touch Export so it becomes the most-recently-used entry...
+	c.get(ImageDirectoryEntryExport)
+	// ...then insert something that forces an eviction: Import should go,
+	// not Export.
+	c.put(ImageDirectoryEntryResource, make([]byte, 4))
+
+	if _, ok := c.get(ImageDirectoryEntryExport); !ok {
+		t.Error("expected Export entry to survive after being touched")
+	}
+	if _, ok := c.get(ImageDirectoryEntryImport); ok {
+		t.Error("expected Import entry to have been evicted")
+	}
+}
+
+func TestParseDirectoryCachesBytes(t *testing.T) {
+	data := buildStreamablePE(1, 64)
+	f, err := NewReader(bytes.NewReader(data), int64(len(data)), &Options{})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	// Point a directory entry's RVA at the lone section so ParseDirectory
+	// can resolve and cache it.
+	oh := ImageOptionalHeader32{NumberOfRvaAndSizes: 16, DataDirectory: make([]DataDirectory, 16)}
+	oh.DataDirectory[ImageDirectoryEntryResource] = DataDirectory{VirtualAddress: 0x1000, Size: 32}
+	f.NtHeader = ImageNtHeader{
+		FileHeader:     ImageFileHeader{SizeOfOptionalHeader: ImageOptionalHeader32FixedSize + 16*8},
+		OptionalHeader: oh,
+	}
+
+	if err := f.ParseDirectory(ImageDirectoryEntryResource); err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	if _, ok := f.cache.get(ImageDirectoryEntryResource); !ok {
+		t.Error("expected the resource directory's bytes to be cached")
+	}
+}
+
+func TestParseStreamingOptionalHeaderPE32(t *testing.T) {
+	optHdr := make([]byte, ImageOptionalHeader32FixedSize+16*8)
+	binary.LittleEndian.PutUint16(optHdr[0:2], imageNtOptionalHdr32Magic)
+	binary.LittleEndian.PutUint32(optHdr[92:96], 16)
+	dirOff := ImageOptionalHeader32FixedSize + int(ImageDirectoryEntryResource)*8
+	binary.LittleEndian.PutUint32(optHdr[dirOff:dirOff+4], 0x2000)
+	binary.LittleEndian.PutUint32(optHdr[dirOff+4:dirOff+8], 0x40)
+
+	oh, ok := parseStreamingOptionalHeader(optHdr).(ImageOptionalHeader32)
+	if !ok {
+		t.Fatalf("parseStreamingOptionalHeader() did not return an ImageOptionalHeader32")
+	}
+	if oh.NumberOfRvaAndSizes != 16 {
+		t.Errorf("NumberOfRvaAndSizes = %d, want 16", oh.NumberOfRvaAndSizes)
+	}
+	if oh.DataDirectory[ImageDirectoryEntryResource].VirtualAddress != 0x2000 {
+		t.Errorf("Resource directory VirtualAddress = 0x%x, want 0x2000",
+			oh.DataDirectory[ImageDirectoryEntryResource].VirtualAddress)
+	}
+}
+
+func TestParseDirectoryWithoutNewReaderFails(t *testing.T) {
+	f := &File{Anomalies: []string{}}
+	if err := f.ParseDirectory(ImageDirectoryEntryImport); err == nil {
+		t.Error("expected an error when the file wasn't opened via NewReader")
+	}
+}
+
+// BenchmarkParseStreaming and BenchmarkParseInMemory compare header-only
+// streaming parsing against fully materializing the image, on a synthetic
+// multi-hundred-MB PE. A real-world sample (test/putty.exe) isn't present in
+// this checkout, so only the synthetic case is benchmarked here.
+func benchmarkPE() []byte {
+	return buildStreamablePE(4, 64*1024*1024)
+}
+
+func BenchmarkParseStreaming(b *testing.B) {
+	data := benchmarkPE()
+	r := bytes.NewReader(data)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewReader(r, int64(len(data)), &Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInMemory(b *testing.B) {
+	data := benchmarkPE()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewBytes(data, &Options{Fast: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}